@@ -9,6 +9,7 @@ import (
 
 const (
 	authTypeAuthKey = "AuthKey"
+	authTypeReadKey = "ReadKey"
 )
 
 // AuthenticationType represents the http request auth type.
@@ -32,6 +33,23 @@ func (ata *AuthenticationTypeAuthKey) Name() string {
 	return ata.name
 }
 
+// AuthenticationTypeReadKey represents the Read Key http auth type used to
+// read private packages.
+type AuthenticationTypeReadKey struct {
+	name    string
+	readKey string
+}
+
+// ReadKey returns the Read Key value.
+func (atr *AuthenticationTypeReadKey) ReadKey() string {
+	return atr.readKey
+}
+
+// Name returns auth type name.
+func (atr *AuthenticationTypeReadKey) Name() string {
+	return atr.name
+}
+
 // AuthenticationTypeUnknown represents an unrecognized http auth type.
 type AuthenticationTypeUnknown struct {
 	name string
@@ -59,6 +77,11 @@ func Parse(auth string) (authType AuthenticationType, err error) {
 			name:    aType,
 			authKey: aVal,
 		}
+	case authTypeReadKey:
+		authType = &AuthenticationTypeReadKey{
+			name:    aType,
+			readKey: aVal,
+		}
 	default:
 		authType = &AuthenticationTypeUnknown{
 			name: aType,
@@ -6,12 +6,91 @@ import (
 
 // Error constants
 var (
-	ErrInternalServer = errors.New("Internal server error occurred")
+	ErrInternalServer     = errors.New("Internal server error occurred")
+	ErrPackageNotFound    = errors.New("Package not found")
+	ErrVersionNotFound    = errors.New("Package version not found")
+	ErrPathNotFound       = errors.New("Path not found")
+	ErrLastVersion        = errors.New("Package has no other version")
+	ErrVersionDeleted     = errors.New("Package version was deleted")
+	ErrPackageExists      = errors.New("Package already exists")
+	ErrSnapshotNotFound   = errors.New("Package snapshot not found")
+	ErrQuarantineNotFound = errors.New("Quarantined repo not found")
+	ErrNothingToSalvage   = errors.New("No intact release found to salvage")
+	ErrReadKeyNotFound    = errors.New("Package read key not found")
+	ErrInvalidUploadPack  = errors.New("Invalid upload-pack request")
+	ErrUnknownFormat      = errors.New("Unknown package archive format")
+	ErrInvalidArchive     = errors.New("Invalid package archive")
+	ErrUnsafeArchive      = errors.New("Package archive has unsafe entries")
+	ErrArchiveTooLarge    = errors.New("Package archive exceeds the limits")
+	ErrInvalidModule      = errors.New("Package is not a valid Go module")
+	ErrSigningDisabled    = errors.New("Release tag signing is disabled")
+	ErrInvalidVersion     = errors.New("Invalid package version")
+	ErrPolicyViolation    = errors.New("Package version violates the publish policy")
+	ErrInvalidNotes       = errors.New("Invalid release notes")
+	ErrVersionExists      = errors.New("Package version already exists")
+	ErrVersionConflict    = errors.New("Package version already exists with different content")
+	ErrInvalidModuleZip   = errors.New("Package version can't be served as a module zip")
 )
 
-// MultiPartReaderMaxMemorySize is the maximum memory size used while reading
+// PackageMetaMaxSize is the maximum size of the JSON package meta in the
 // multipart/form-data content.
-const MultiPartReaderMaxMemorySize = 10 * 1024 * 1024
+const PackageMetaMaxSize = 1024 * 1024
+
+const (
+	// HeaderPackageType is the request header which holds the package type,
+	// public or private, on raw package data uploads.
+	HeaderPackageType = "X-Package-Type"
+
+	// HeaderPackageOwnerName is the request header which holds the package
+	// owner name on raw package data uploads.
+	HeaderPackageOwnerName = "X-Package-Owner-Name"
+
+	// HeaderPackageOwnerUsername is the request header which holds the package
+	// owner username on raw package data uploads.
+	HeaderPackageOwnerUsername = "X-Package-Owner-Username"
+
+	// HeaderPackageOwnerEmail is the request header which holds the package
+	// owner public email on raw package data uploads.
+	HeaderPackageOwnerEmail = "X-Package-Owner-Email"
+
+	// HeaderPackageReleaseNotes is the request header which holds the
+	// percent-encoded markdown release notes on raw package data uploads.
+	HeaderPackageReleaseNotes = "X-Package-Release-Notes"
+
+	// HeaderIdempotencyKey is the request header which identifies a publish
+	// request, so that its retries aren't taken as duplicate versions.
+	HeaderIdempotencyKey = "Idempotency-Key"
+)
+
+// IdempotencyKeyMaxLength is the maximum length of the idempotency key of
+// a publish request.
+const IdempotencyKeyMaxLength = 255
+
+const (
+	// ArchiveFormatTarGZ represents the gzip compressed tar archive format.
+	ArchiveFormatTarGZ = "tar.gz"
+
+	// ArchiveFormatZip represents the zip archive format.
+	ArchiveFormatZip = "zip"
+
+	// ArchiveFormatTar represents the uncompressed tar archive format.
+	ArchiveFormatTar = "tar"
+
+	// ArchiveFormatTarZst represents the zstd compressed tar archive format.
+	ArchiveFormatTarZst = "tar.zst"
+
+	// ArchiveFormatTarXZ represents the xz compressed tar archive format.
+	ArchiveFormatTarXZ = "tar.xz"
+)
+
+const (
+	// DefaultPerPage is the number of items returned per page when the
+	// request doesn't specify it.
+	DefaultPerPage = 30
+
+	// MaxPerPage is the maximum number of items returned per page.
+	MaxPerPage = 100
+)
 
 const (
 	// RepoAutoCommitterName represents the commiter name for auto generated
@@ -31,6 +110,46 @@ const (
 	RepoAutoTaggerEmail = "gopx@gopx.io"
 )
 
+const (
+	// TagSignFormatOpenPGP represents the release tag signatures made with
+	// an OpenPGP key.
+	TagSignFormatOpenPGP = "openpgp"
+
+	// TagSignFormatSSH represents the release tag signatures made with an
+	// SSH key.
+	TagSignFormatSSH = "ssh"
+)
+
+const (
+	// TagTrailerGoSum is the release tag message trailer which holds the h1
+	// dirhash of the module zip of the release.
+	TagTrailerGoSum = "Go-Sum"
+
+	// TagTrailerGoModSum is the release tag message trailer which holds the
+	// h1 dirhash of the go.mod of the release.
+	TagTrailerGoModSum = "Go-Mod-Sum"
+
+	// TagTrailerIdempotencyKey is the release tag message trailer which holds
+	// the idempotency key of the publish request of the release.
+	TagTrailerIdempotencyKey = "Idempotency-Key"
+)
+
 // GitExportRepoFileName is the file name which existence is responsible
 // for package exporting status.
 const GitExportRepoFileName = "git-daemon-export-ok"
+
+// RepoTombstonesFileName is the file name inside package repo which records
+// the deleted versions of the package.
+const RepoTombstonesFileName = "gopx-tombstones.json"
+
+// RepoQuarantineReportFileName is the file name inside quarantined repo
+// which holds the diagnostic report of the repo.
+const RepoQuarantineReportFileName = "gopx-quarantine.json"
+
+// RepoReadKeysFileName is the file name inside private package repo which
+// holds the hashed read credentials of the package.
+const RepoReadKeysFileName = "gopx-read-keys.json"
+
+// RepoPublishPolicyFileName is the file name inside package repo which
+// stores the publish policy overrides of the package.
+const RepoPublishPolicyFileName = "gopx-publish-policy.json"
@@ -5,6 +5,9 @@ import (
 	"time"
 )
 
+// formattedTimeLayout is the time layout produced by FormatTime.
+const formattedTimeLayout = "2006_01_02T15_04_05"
+
 // FormatTime formats time to append date to any path.
 func FormatTime(t time.Time) string {
 	return fmt.Sprintf(
@@ -17,3 +20,8 @@ func FormatTime(t time.Time) string {
 		t.Second(),
 	)
 }
+
+// ParseTime parses the time formatted by FormatTime.
+func ParseTime(s string) (time.Time, error) {
+	return time.ParseInLocation(formattedTimeLayout, s, time.Local)
+}
@@ -0,0 +1,77 @@
+package helper
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+	"gopx.io/gopx-vcs-api/api/v1/constants"
+)
+
+// ParsePagination parses the page and perPage query parameters of the
+// http request.
+func ParsePagination(r *http.Request) (page, perPage int, err error) {
+	query := r.URL.Query()
+	page, perPage = 1, constants.DefaultPerPage
+
+	if v := query.Get("page"); v != "" {
+		page, err = strconv.Atoi(v)
+		if err != nil || page < 1 {
+			err = errors.New("Query param page must be a positive integer")
+			return
+		}
+	}
+
+	if v := query.Get("perPage"); v != "" {
+		perPage, err = strconv.Atoi(v)
+		if err != nil || perPage < 1 {
+			err = errors.New("Query param perPage must be a positive integer")
+			return
+		}
+	}
+
+	if perPage > constants.MaxPerPage {
+		perPage = constants.MaxPerPage
+	}
+
+	return
+}
+
+// SetPaginationLinks writes the Link header containing the first, prev,
+// next and last page urls of the http request.
+func SetPaginationLinks(w http.ResponseWriter, r *http.Request, page, perPage, total int) {
+	lastPage := (total + perPage - 1) / perPage
+	if lastPage < 1 {
+		lastPage = 1
+	}
+
+	links := []string{}
+	addLink := func(p int, rel string) {
+		u := *r.URL
+		query := u.Query()
+		query.Set("page", strconv.Itoa(p))
+		query.Set("perPage", strconv.Itoa(perPage))
+		u.RawQuery = query.Encode()
+		links = append(links, fmt.Sprintf("<%s>; rel=\"%s\"", u.RequestURI(), rel))
+	}
+
+	if page > 1 {
+		addLink(1, "first")
+		prevPage := page - 1
+		if prevPage > lastPage {
+			prevPage = lastPage
+		}
+		addLink(prevPage, "prev")
+	}
+
+	if page < lastPage {
+		addLink(page+1, "next")
+		addLink(lastPage, "last")
+	}
+
+	if len(links) > 0 {
+		w.Header().Set("Link", strings.Join(links, ", "))
+	}
+}
@@ -4,8 +4,10 @@ import (
 	"bytes"
 	"encoding/json"
 	"fmt"
+	"io"
 	"net/http"
 	"strconv"
+	"time"
 
 	"gopx.io/gopx-common/log"
 	errorCtrl "gopx.io/gopx-vcs-api/pkg/controller/error"
@@ -14,16 +16,22 @@ import (
 
 func setBasicHeaders(headers http.Header) {
 	headers.Set("Server", "GoPx.io")
-	headers.Set("Access-Control-Expose-Headers", "Content-Length, Server, Date, Status")
+	headers.Set("Access-Control-Expose-Headers", "Content-Length, Server, Date, Status, Link")
 	headers.Set("Access-Control-Allow-Origin", "*")
 }
 
 // WriteResponse writes JSON data to the client with the specified status code.
 func WriteResponse(w http.ResponseWriter, r *http.Request, data []byte, statusCode int) {
+	WriteResponseContent(w, r, data, "application/json; charset=utf-8", statusCode)
+}
+
+// WriteResponseContent writes data of the specified content type to the client
+// with the specified status code.
+func WriteResponseContent(w http.ResponseWriter, r *http.Request, data []byte, contentType string, statusCode int) {
 	headers := w.Header()
 	setBasicHeaders(headers)
 
-	headers.Set("Content-Type", "application/json; charset=utf-8")
+	headers.Set("Content-Type", contentType)
 	headers.Set("Content-Length", strconv.Itoa(len(data)))
 	headers.Set("Status", fmt.Sprintf("%d %s", statusCode, http.StatusText(statusCode)))
 
@@ -33,6 +41,30 @@ func WriteResponse(w http.ResponseWriter, r *http.Request, data []byte, statusCo
 	}
 }
 
+// WriteStreamHeader writes the response headers for the content which
+// is streamed to the client afterwards.
+func WriteStreamHeader(w http.ResponseWriter, r *http.Request, contentType string, statusCode int) {
+	headers := w.Header()
+	setBasicHeaders(headers)
+
+	headers.Set("Content-Type", contentType)
+	headers.Set("Status", fmt.Sprintf("%d %s", statusCode, http.StatusText(statusCode)))
+
+	w.WriteHeader(statusCode)
+}
+
+// ServeContent writes the file content to the client with the detected
+// Content-Type, honoring the Range and conditional request headers.
+func ServeContent(w http.ResponseWriter, r *http.Request, name string, modTime time.Time, content io.ReadSeeker) {
+	headers := w.Header()
+	setBasicHeaders(headers)
+
+	headers.Set("X-Content-Type-Options", "nosniff")
+	headers.Set("Content-Security-Policy", "default-src 'none'; sandbox")
+
+	http.ServeContent(w, r, name, modTime, content)
+}
+
 // WriteResponseValue writes the input golang value in the form of JSON encoding
 // to the client with the specified status code.
 func WriteResponseValue(w http.ResponseWriter, r *http.Request, data interface{}, statusCode int) {
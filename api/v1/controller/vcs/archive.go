@@ -0,0 +1,177 @@
+package vcs
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/pkg/errors"
+	"gopkg.in/src-d/go-git.v4/plumbing/filemode"
+	"gopkg.in/src-d/go-git.v4/plumbing/object"
+	"gopx.io/gopx-vcs-api/api/v1/constants"
+)
+
+// PackageArchive represents the source archive of a package version
+// which is streamed straight out of the package repo.
+type PackageArchive struct {
+	FileName    string
+	ContentType string
+	ModTime     time.Time
+	format      string
+	commit      *object.Commit
+}
+
+// PackageVersionArchive resolves the source archive of a package version
+// in the requested format.
+func PackageVersionArchive(pkgName, version, format string) (archive *PackageArchive, err error) {
+	var contentType string
+	switch format {
+	case constants.ArchiveFormatTarGZ:
+		contentType = "application/gzip"
+	case constants.ArchiveFormatZip:
+		contentType = "application/zip"
+	default:
+		err = errors.Errorf("Unknown archive format %s", format)
+		return
+	}
+
+	tag, commit, err := packageVersionCommit(pkgName, version)
+	if err != nil {
+		return
+	}
+
+	archive = &PackageArchive{
+		FileName:    fmt.Sprintf("%s-%s.%s", pkgName, tag.Name, format),
+		ContentType: contentType,
+		ModTime:     commit.Committer.When,
+		format:      format,
+		commit:      commit,
+	}
+
+	return
+}
+
+// Write writes the archive content to w. The files are stored relative to
+// the archive root, the same layout as expected on package registration.
+func (pa *PackageArchive) Write(w io.Writer) (err error) {
+	files, err := pa.commit.Files()
+	if err != nil {
+		err = errors.Wrap(err, "Couldn't access commit files")
+		return
+	}
+
+	switch pa.format {
+	case constants.ArchiveFormatTarGZ:
+		err = pa.writeTarGZ(w, files)
+	case constants.ArchiveFormatZip:
+		err = pa.writeZip(w, files)
+	}
+
+	return
+}
+
+func (pa *PackageArchive) writeTarGZ(w io.Writer, files *object.FileIter) (err error) {
+	gw := gzip.NewWriter(w)
+	tw := tar.NewWriter(gw)
+
+	err = files.ForEach(func(f *object.File) error {
+		mode, err := f.Mode.ToOSFileMode()
+		if err != nil {
+			return errors.Wrapf(err, "Invalid file mode of %s", f.Name)
+		}
+
+		hdr := &tar.Header{
+			Name:    f.Name,
+			Mode:    int64(mode.Perm()),
+			ModTime: pa.ModTime,
+		}
+
+		if f.Mode == filemode.Symlink {
+			target, err := f.Contents()
+			if err != nil {
+				return errors.Wrapf(err, "Couldn't read symlink %s", f.Name)
+			}
+			hdr.Typeflag = tar.TypeSymlink
+			hdr.Linkname = target
+			return tw.WriteHeader(hdr)
+		}
+
+		hdr.Typeflag = tar.TypeReg
+		hdr.Size = f.Size
+
+		err = tw.WriteHeader(hdr)
+		if err != nil {
+			return err
+		}
+
+		return copyFileContents(tw, f)
+	})
+	if err != nil {
+		err = errors.Wrap(err, "Failed to write tar archive")
+		return
+	}
+
+	err = tw.Close()
+	if err != nil {
+		return
+	}
+
+	err = gw.Close()
+
+	return
+}
+
+func (pa *PackageArchive) writeZip(w io.Writer, files *object.FileIter) (err error) {
+	zw := zip.NewWriter(w)
+
+	err = files.ForEach(func(f *object.File) error {
+		mode, err := f.Mode.ToOSFileMode()
+		if err != nil {
+			return errors.Wrapf(err, "Invalid file mode of %s", f.Name)
+		}
+
+		hdr := &zip.FileHeader{
+			Name:     f.Name,
+			Method:   zip.Deflate,
+			Modified: pa.ModTime,
+		}
+
+		if f.Mode == filemode.Symlink {
+			hdr.SetMode(os.ModeSymlink | 0777)
+		} else {
+			hdr.SetMode(mode.Perm())
+		}
+
+		fw, err := zw.CreateHeader(hdr)
+		if err != nil {
+			return err
+		}
+
+		return copyFileContents(fw, f)
+	})
+	if err != nil {
+		err = errors.Wrap(err, "Failed to write zip archive")
+		return
+	}
+
+	err = zw.Close()
+
+	return
+}
+
+func copyFileContents(w io.Writer, f *object.File) (err error) {
+	r, err := f.Reader()
+	if err != nil {
+		err = errors.Wrapf(err, "Couldn't read file %s", f.Name)
+		return
+	}
+	defer r.Close()
+
+	_, err = io.Copy(w, r)
+
+	return
+}
@@ -0,0 +1,53 @@
+package vcs
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"os"
+
+	"github.com/pkg/errors"
+	"golang.org/x/mod/module"
+	"golang.org/x/mod/sumdb/dirhash"
+	modzip "golang.org/x/mod/zip"
+	"gopkg.in/src-d/go-git.v4/plumbing/object"
+)
+
+// commitModuleSums computes the go.sum hashes of the release commit served
+// as the Go module version, the h1 dirhash of the module zip and the one of
+// its go.mod, the same way the go command computes them after download.
+func commitModuleSums(modPath, version string, commit *object.Commit) (sum, goModSum string, err error) {
+	files, err := commitModuleZipFiles(commit)
+	if err != nil {
+		return
+	}
+
+	zipFile, err := ioutil.TempFile("", "gopx-module-zip-")
+	if err != nil {
+		return
+	}
+	defer os.Remove(zipFile.Name())
+	defer zipFile.Close()
+
+	err = modzip.Create(zipFile, module.Version{Path: modPath, Version: version}, files)
+	if err != nil {
+		err = errors.Wrapf(err, "Couldn't create the module zip of %s@%s", modPath, version)
+		return
+	}
+
+	sum, err = dirhash.HashZip(zipFile.Name(), dirhash.Hash1)
+	if err != nil {
+		err = errors.Wrapf(err, "Couldn't hash the module zip of %s@%s", modPath, version)
+		return
+	}
+
+	goMod, err := commitGoMod(commit)
+	if err != nil {
+		return
+	}
+
+	goModSum, err = dirhash.Hash1([]string{"go.mod"}, func(string) (io.ReadCloser, error) {
+		return ioutil.NopCloser(bytes.NewReader(goMod)), nil
+	})
+	return
+}
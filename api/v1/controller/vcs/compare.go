@@ -0,0 +1,93 @@
+package vcs
+
+import (
+	"strings"
+
+	"github.com/pkg/errors"
+	fdiff "gopkg.in/src-d/go-git.v4/plumbing/format/diff"
+	"gopx.io/gopx-vcs-api/api/v1/types"
+)
+
+// ComparePackageVersions computes the changes between two versions
+// of a package, optionally including the unified patch.
+func ComparePackageVersions(pkgName, fromVersion, toVersion string, withPatch bool) (cmp *types.PackageComparison, err error) {
+	fromTag, fromCommit, err := packageVersionCommit(pkgName, fromVersion)
+	if err != nil {
+		return
+	}
+
+	toTag, toCommit, err := packageVersionCommit(pkgName, toVersion)
+	if err != nil {
+		return
+	}
+
+	patch, err := fromCommit.Patch(toCommit)
+	if err != nil {
+		err = errors.Wrapf(err, "Failed to compute patch between %s and %s [%s]", fromTag.Name, toTag.Name, pkgName)
+		return
+	}
+
+	cmp = &types.PackageComparison{
+		From:  fromTag.Name,
+		To:    toTag.Name,
+		Files: []*types.FileChange{},
+	}
+
+	for _, fp := range patch.FilePatches() {
+		change := fileChange(fp)
+		cmp.Additions += change.Additions
+		cmp.Deletions += change.Deletions
+		cmp.Files = append(cmp.Files, change)
+	}
+
+	if withPatch {
+		cmp.Patch = patch.String()
+	}
+
+	return
+}
+
+func fileChange(fp fdiff.FilePatch) *types.FileChange {
+	change := &types.FileChange{Binary: fp.IsBinary()}
+
+	from, to := fp.Files()
+	switch {
+	case from == nil:
+		change.Path = to.Path()
+		change.Status = "added"
+	case to == nil:
+		change.Path = from.Path()
+		change.Status = "removed"
+	case from.Path() != to.Path():
+		change.Path = to.Path()
+		change.PreviousPath = from.Path()
+		change.Status = "renamed"
+	default:
+		change.Path = to.Path()
+		change.Status = "modified"
+	}
+
+	for _, chunk := range fp.Chunks() {
+		switch chunk.Type() {
+		case fdiff.Add:
+			change.Additions += countLines(chunk.Content())
+		case fdiff.Delete:
+			change.Deletions += countLines(chunk.Content())
+		}
+	}
+
+	return change
+}
+
+func countLines(s string) int {
+	if s == "" {
+		return 0
+	}
+
+	n := strings.Count(s, "\n")
+	if !strings.HasSuffix(s, "\n") {
+		n++
+	}
+
+	return n
+}
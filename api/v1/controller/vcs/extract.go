@@ -0,0 +1,547 @@
+package vcs
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/pkg/errors"
+	"github.com/ulikunitz/xz"
+	"gopx.io/gopx-vcs-api/api/v1/constants"
+	"gopx.io/gopx-vcs-api/pkg/config"
+)
+
+// archiveMagic maps the leading bytes of the compressed archive formats.
+var archiveMagic = []struct {
+	format string
+	magic  []byte
+}{
+	{constants.ArchiveFormatTarGZ, []byte{0x1f, 0x8b}},
+	{constants.ArchiveFormatZip, []byte("PK\x03\x04")},
+	{constants.ArchiveFormatZip, []byte("PK\x05\x06")},
+	{constants.ArchiveFormatTarZst, []byte{0x28, 0xb5, 0x2f, 0xfd}},
+	{constants.ArchiveFormatTarXZ, []byte{0xfd, '7', 'z', 'X', 'Z', 0x00}},
+}
+
+// tarMagicOffset is the offset of the ustar magic in a tar header.
+const tarMagicOffset = 257
+
+// compressionRatioMinSize is the extracted size from which the compression
+// ratio limit is enforced, so that the small and highly compressible
+// packages aren't refused.
+const compressionRatioMinSize = 1024 * 1024
+
+// extractPackageData extracts the package archive into dst. The archive
+// format is detected from the content if it isn't specified. The entries
+// which could escape dst or can't be stored in a package repo are refused,
+// and the extraction stops as soon as a limit of the vcs config is exceeded.
+func extractPackageData(dst string, data io.Reader, format string) (err error) {
+	ex := &archiveExtractor{
+		dst:   dst,
+		in:    &countReader{r: data},
+		links: map[string]string{},
+	}
+	br := bufio.NewReader(ex.in)
+
+	if format == "" {
+		format, err = detectArchiveFormat(br)
+		if err != nil {
+			return
+		}
+	}
+
+	switch format {
+	case constants.ArchiveFormatTarGZ:
+		var gr *gzip.Reader
+		gr, err = gzip.NewReader(br)
+		if err != nil {
+			err = errors.Wrap(constants.ErrInvalidArchive, err.Error())
+			return
+		}
+		defer gr.Close()
+		err = ex.extractTar(gr)
+	case constants.ArchiveFormatTar:
+		err = ex.extractTar(br)
+	case constants.ArchiveFormatTarZst:
+		var zr *zstd.Decoder
+		zr, err = zstd.NewReader(br)
+		if err != nil {
+			err = errors.Wrap(constants.ErrInvalidArchive, err.Error())
+			return
+		}
+		defer zr.Close()
+		err = ex.extractTar(zr)
+	case constants.ArchiveFormatTarXZ:
+		var xr *xz.Reader
+		xr, err = xz.NewReader(br)
+		if err != nil {
+			err = errors.Wrap(constants.ErrInvalidArchive, err.Error())
+			return
+		}
+		err = ex.extractTar(xr)
+	case constants.ArchiveFormatZip:
+		err = ex.extractZip(br)
+	default:
+		err = errors.Wrapf(constants.ErrUnknownFormat, "Format %s is not supported", format)
+	}
+	if err != nil {
+		return
+	}
+
+	err = ex.checkSymlinks()
+
+	return
+}
+
+// detectArchiveFormat detects the archive format from the magic bytes at
+// the start of the content.
+func detectArchiveFormat(br *bufio.Reader) (format string, err error) {
+	head, err := br.Peek(tarMagicOffset + 5)
+	if err != nil && err != io.EOF {
+		return
+	}
+	err = nil
+
+	for _, am := range archiveMagic {
+		if bytes.HasPrefix(head, am.magic) {
+			format = am.format
+			return
+		}
+	}
+
+	if len(head) == tarMagicOffset+5 && bytes.Equal(head[tarMagicOffset:], []byte("ustar")) {
+		format = constants.ArchiveFormatTar
+		return
+	}
+
+	err = errors.Wrap(constants.ErrUnknownFormat, "Format couldn't be detected from the package data")
+	return
+}
+
+// archiveExtractor extracts the entries of a package archive into dst and
+// keeps track of the extracted size and entries against the limits.
+type archiveExtractor struct {
+	dst   string
+	in    *countReader
+	size  int64
+	count int
+
+	// links maps the extracted symlinks to their targets.
+	links map[string]string
+}
+
+func (ex *archiveExtractor) extractTar(r io.Reader) error {
+	tr := tar.NewReader(r)
+
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return errors.Wrap(constants.ErrInvalidArchive, err.Error())
+		}
+
+		switch hdr.Typeflag {
+		case tar.TypeDir:
+			err = ex.extractDir(hdr.Name)
+		case tar.TypeReg, tar.TypeRegA:
+			err = ex.extractFile(hdr.Name, hdr.FileInfo().Mode(), hdr.Size, tr)
+		case tar.TypeSymlink:
+			err = ex.extractSymlink(hdr.Name, hdr.Linkname)
+		case tar.TypeLink, tar.TypeChar, tar.TypeBlock, tar.TypeFifo:
+			err = errors.Wrapf(constants.ErrUnsafeArchive, "Entry %s is a link or special file", hdr.Name)
+		default:
+			// The other entry types hold no package content.
+			continue
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// extractZip extracts the zip archive. Zip needs random access, so the
+// content is spooled to a temp file first. The path prefix of the module
+// zips, <module>@<version>/, is stripped off.
+func (ex *archiveExtractor) extractZip(r io.Reader) (err error) {
+	tmpFile, err := ioutil.TempFile("", "gopx-package-data-")
+	if err != nil {
+		return
+	}
+	defer os.Remove(tmpFile.Name())
+	defer tmpFile.Close()
+
+	// The compressed content can't be larger than the extracted one by
+	// much, so the spooled file is bounded by the total size limit too.
+	if max := config.VCS.MaxPackageSize; max > 0 {
+		r = io.LimitReader(r, max+1)
+	}
+
+	size, err := io.Copy(tmpFile, r)
+	if err != nil {
+		return
+	}
+
+	if max := config.VCS.MaxPackageSize; max > 0 && size > max {
+		err = errors.Wrapf(constants.ErrArchiveTooLarge, "Archive is larger than %d bytes", max)
+		return
+	}
+
+	zr, err := zip.NewReader(tmpFile, size)
+	if err != nil {
+		err = errors.Wrap(constants.ErrInvalidArchive, err.Error())
+		return
+	}
+
+	prefix := moduleZipPrefix(zr.File)
+
+	for _, f := range zr.File {
+		name := strings.TrimPrefix(f.Name, prefix)
+		if name == "" {
+			continue
+		}
+
+		mode := f.Mode()
+		switch {
+		case mode.IsDir():
+			err = ex.extractDir(name)
+		case mode&os.ModeSymlink != 0:
+			err = ex.extractZipSymlink(name, f)
+		case mode.IsRegular():
+			err = ex.extractZipFile(name, f)
+		default:
+			err = errors.Wrapf(constants.ErrUnsafeArchive, "Entry %s is a special file", name)
+		}
+		if err != nil {
+			return
+		}
+	}
+
+	return
+}
+
+func (ex *archiveExtractor) extractZipFile(name string, f *zip.File) (err error) {
+	rc, err := f.Open()
+	if err != nil {
+		err = errors.Wrap(constants.ErrInvalidArchive, err.Error())
+		return
+	}
+	defer rc.Close()
+
+	err = ex.extractFile(name, f.Mode(), int64(f.UncompressedSize64), rc)
+	return
+}
+
+func (ex *archiveExtractor) extractZipSymlink(name string, f *zip.File) (err error) {
+	rc, err := f.Open()
+	if err != nil {
+		err = errors.Wrap(constants.ErrInvalidArchive, err.Error())
+		return
+	}
+	defer rc.Close()
+
+	// Symlink targets are bounded by the path length limits of the OS.
+	target, err := ioutil.ReadAll(io.LimitReader(archiveReader{rc}, 4096))
+	if err != nil {
+		return
+	}
+
+	err = ex.extractSymlink(name, string(target))
+	return
+}
+
+// moduleZipPrefix returns the <module>@<version>/ prefix shared by all the
+// entries of a Go module zip, or an empty string for the other zips.
+func moduleZipPrefix(files []*zip.File) string {
+	if len(files) == 0 {
+		return ""
+	}
+
+	name := files[0].Name
+	i := strings.Index(name, "@")
+	if i < 0 {
+		return ""
+	}
+
+	j := strings.Index(name[i:], "/")
+	if j < 0 {
+		return ""
+	}
+
+	prefix := name[:i+j+1]
+	for _, f := range files {
+		if !strings.HasPrefix(f.Name, prefix) {
+			return ""
+		}
+	}
+
+	return prefix
+}
+
+// entryPath returns the destination path of an archive entry. The absolute
+// names, the names with .. or .git elements and the names which resolve
+// through an already extracted symlink are refused.
+func (ex *archiveExtractor) entryPath(name string) (string, error) {
+	if path.IsAbs(name) || filepath.IsAbs(name) || filepath.VolumeName(name) != "" {
+		return "", errors.Wrapf(constants.ErrUnsafeArchive, "Entry %s has an absolute path", name)
+	}
+
+	name = strings.TrimSuffix(name, "/")
+	elems := strings.Split(name, "/")
+	for _, elem := range elems {
+		switch {
+		case elem == "..":
+			return "", errors.Wrapf(constants.ErrUnsafeArchive, "Entry %s has a path traversal", name)
+		case strings.EqualFold(elem, ".git"):
+			return "", errors.Wrapf(constants.ErrUnsafeArchive, "Entry %s is inside a nested .git dir", name)
+		case strings.ContainsRune(elem, '\\'):
+			return "", errors.Wrapf(constants.ErrUnsafeArchive, "Entry %s has a backslash in its path", name)
+		}
+	}
+
+	p := ex.dst
+	for _, elem := range elems {
+		if elem == "" || elem == "." {
+			continue
+		}
+		p = filepath.Join(p, elem)
+
+		fi, err := os.Lstat(p)
+		if os.IsNotExist(err) {
+			break
+		}
+		if err != nil {
+			return "", err
+		}
+		if fi.Mode()&os.ModeSymlink != 0 {
+			return "", errors.Wrapf(constants.ErrUnsafeArchive, "Entry %s resolves through a symlink", name)
+		}
+	}
+
+	return filepath.Join(ex.dst, filepath.FromSlash(name)), nil
+}
+
+// addEntry counts a new entry against the file count limit.
+func (ex *archiveExtractor) addEntry() error {
+	ex.count++
+	if max := config.VCS.MaxPackageFileCount; max > 0 && ex.count > max {
+		return errors.Wrapf(constants.ErrArchiveTooLarge, "Archive has more than %d entries", max)
+	}
+	return nil
+}
+
+// addSize counts the extracted bytes against the total size and the
+// compression ratio limits.
+func (ex *archiveExtractor) addSize(n int64) error {
+	ex.size += n
+	if max := config.VCS.MaxPackageSize; max > 0 && ex.size > max {
+		return errors.Wrapf(constants.ErrArchiveTooLarge, "Archive extracts to more than %d bytes", max)
+	}
+
+	maxRatio := config.VCS.MaxCompressionRatio
+	if maxRatio > 0 && ex.size > compressionRatioMinSize && ex.in.n > 0 && ex.size/ex.in.n > maxRatio {
+		return errors.Wrapf(constants.ErrArchiveTooLarge, "Archive compression ratio is higher than %d", maxRatio)
+	}
+
+	return nil
+}
+
+func (ex *archiveExtractor) extractDir(name string) (err error) {
+	err = ex.addEntry()
+	if err != nil {
+		return
+	}
+
+	p, err := ex.entryPath(name)
+	if err != nil {
+		return
+	}
+
+	err = os.MkdirAll(p, 0755)
+	return
+}
+
+func (ex *archiveExtractor) extractFile(name string, mode os.FileMode, size int64, r io.Reader) (err error) {
+	err = ex.addEntry()
+	if err != nil {
+		return
+	}
+
+	// The declared size can't be trusted, it's only used to fail early.
+	maxFileSize := config.VCS.MaxPackageFileSize
+	if maxFileSize > 0 && size > maxFileSize {
+		err = errors.Wrapf(constants.ErrArchiveTooLarge, "Entry %s is larger than %d bytes", name, maxFileSize)
+		return
+	}
+
+	p, err := ex.entryPath(name)
+	if err != nil {
+		return
+	}
+
+	err = os.MkdirAll(filepath.Dir(p), 0755)
+	if err != nil {
+		return
+	}
+
+	// Only the executable bit is tracked by git.
+	perm := os.FileMode(0644)
+	if mode&0111 != 0 {
+		perm = 0755
+	}
+
+	f, err := os.OpenFile(p, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, perm)
+	if err != nil {
+		return
+	}
+	defer f.Close()
+
+	buf := make([]byte, 32*1024)
+	var written int64
+	ar := archiveReader{r}
+	for {
+		n, rErr := ar.Read(buf)
+		if n > 0 {
+			written += int64(n)
+			if maxFileSize > 0 && written > maxFileSize {
+				err = errors.Wrapf(constants.ErrArchiveTooLarge, "Entry %s is larger than %d bytes", name, maxFileSize)
+				return
+			}
+
+			err = ex.addSize(int64(n))
+			if err != nil {
+				return
+			}
+
+			_, err = f.Write(buf[:n])
+			if err != nil {
+				return
+			}
+		}
+		if rErr == io.EOF {
+			return
+		}
+		if rErr != nil {
+			err = rErr
+			return
+		}
+	}
+}
+
+func (ex *archiveExtractor) extractSymlink(name, target string) (err error) {
+	err = ex.addEntry()
+	if err != nil {
+		return
+	}
+
+	p, err := ex.entryPath(name)
+	if err != nil {
+		return
+	}
+
+	// The target is resolved lexically here, which only holds if it doesn't
+	// walk through another symlink. That's checked by checkSymlinks once all
+	// the symlinks are extracted.
+	if target == "" || path.IsAbs(target) || filepath.IsAbs(target) || strings.ContainsRune(target, '\\') {
+		err = errors.Wrapf(constants.ErrUnsafeArchive, "Entry %s links to %s outside of the package root", name, target)
+		return
+	}
+
+	resolved := path.Join(path.Dir(strings.TrimSuffix(name, "/")), target)
+	if resolved == ".." || strings.HasPrefix(resolved, "../") {
+		err = errors.Wrapf(constants.ErrUnsafeArchive, "Entry %s links to %s outside of the package root", name, target)
+		return
+	}
+
+	for _, elem := range strings.Split(resolved, "/") {
+		if strings.EqualFold(elem, ".git") {
+			err = errors.Wrapf(constants.ErrUnsafeArchive, "Entry %s links into the .git dir", name)
+			return
+		}
+	}
+
+	err = os.MkdirAll(filepath.Dir(p), 0755)
+	if err != nil {
+		return
+	}
+
+	err = os.Symlink(target, p)
+	if err != nil {
+		return
+	}
+
+	ex.links[path.Clean(strings.TrimSuffix(name, "/"))] = target
+	return
+}
+
+// checkSymlinks refuses the symlinks whose target walks through another
+// symlink, e.g. d/l -> .. is inside the package root but e -> d/l/.. is
+// not. It runs after the extraction as the symlink walked through can
+// come after the one which walks through it in the archive.
+func (ex *archiveExtractor) checkSymlinks() error {
+	for name, target := range ex.links {
+		var cur []string
+		if dir := path.Dir(name); dir != "." {
+			cur = strings.Split(dir, "/")
+		}
+
+		elems := strings.Split(target, "/")
+		for i, elem := range elems {
+			switch elem {
+			case "", ".":
+				continue
+			case "..":
+				if len(cur) == 0 {
+					return errors.Wrapf(constants.ErrUnsafeArchive, "Entry %s links to %s outside of the package root", name, target)
+				}
+				cur = cur[:len(cur)-1]
+			default:
+				cur = append(cur, elem)
+			}
+
+			// The last element can be a symlink, which is checked on its own.
+			if _, ok := ex.links[strings.Join(cur, "/")]; ok && i < len(elems)-1 {
+				return errors.Wrapf(constants.ErrUnsafeArchive, "Entry %s links to %s through another symlink", name, target)
+			}
+		}
+	}
+
+	return nil
+}
+
+// archiveReader marks the read errors of the archive content as the errors
+// of an invalid archive, apart from the write errors of the extraction.
+type archiveReader struct {
+	r io.Reader
+}
+
+func (ar archiveReader) Read(p []byte) (n int, err error) {
+	n, err = ar.r.Read(p)
+	if err != nil && err != io.EOF {
+		err = errors.Wrap(constants.ErrInvalidArchive, err.Error())
+	}
+	return
+}
+
+// countReader counts the bytes read of the raw package data to compute the
+// compression ratio.
+type countReader struct {
+	r io.Reader
+	n int64
+}
+
+func (cr *countReader) Read(p []byte) (n int, err error) {
+	n, err = cr.r.Read(p)
+	cr.n += int64(n)
+	return
+}
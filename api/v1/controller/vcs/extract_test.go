@@ -0,0 +1,168 @@
+package vcs
+
+import (
+	"archive/tar"
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/pkg/errors"
+	"gopx.io/gopx-vcs-api/api/v1/constants"
+)
+
+type tarEntry struct {
+	name     string
+	typeflag byte
+	body     string
+	linkname string
+}
+
+func buildTar(t *testing.T, entries []tarEntry) *bytes.Buffer {
+	buf := &bytes.Buffer{}
+	tw := tar.NewWriter(buf)
+
+	for _, e := range entries {
+		typeflag := e.typeflag
+		if typeflag == 0 {
+			typeflag = tar.TypeReg
+		}
+
+		hdr := &tar.Header{
+			Name:     e.name,
+			Typeflag: typeflag,
+			Linkname: e.linkname,
+			Mode:     0644,
+		}
+		if typeflag == tar.TypeReg {
+			hdr.Size = int64(len(e.body))
+		}
+
+		err := tw.WriteHeader(hdr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, err = tw.Write([]byte(e.body))
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	err := tw.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return buf
+}
+
+func TestExtractPackageDataEscapes(t *testing.T) {
+	cases := []struct {
+		name    string
+		entries []tarEntry
+		err     error
+	}{
+		{
+			name:    "regular files",
+			entries: []tarEntry{{name: "a.go", body: "package a"}, {name: "sub/b.go", body: "package sub"}},
+		},
+		{
+			name:    "symlink inside the root",
+			entries: []tarEntry{{name: "a.go", body: "package a"}, {name: "d/l", typeflag: tar.TypeSymlink, linkname: "../a.go"}},
+		},
+		{
+			name:    "symlink to a symlink",
+			entries: []tarEntry{{name: "d/l", typeflag: tar.TypeSymlink, linkname: ".."}, {name: "e", typeflag: tar.TypeSymlink, linkname: "d/l"}},
+		},
+		{
+			name:    "absolute path",
+			entries: []tarEntry{{name: "/tmp/x", body: "x"}},
+			err:     constants.ErrUnsafeArchive,
+		},
+		{
+			name:    "path traversal",
+			entries: []tarEntry{{name: "../x", body: "x"}},
+			err:     constants.ErrUnsafeArchive,
+		},
+		{
+			name:    "nested path traversal",
+			entries: []tarEntry{{name: "a/../../x", body: "x"}},
+			err:     constants.ErrUnsafeArchive,
+		},
+		{
+			name:    "backslash",
+			entries: []tarEntry{{name: `a\..\..\x`, body: "x"}},
+			err:     constants.ErrUnsafeArchive,
+		},
+		{
+			name:    "nested .git dir",
+			entries: []tarEntry{{name: "sub/.GIT/config", body: "x"}},
+			err:     constants.ErrUnsafeArchive,
+		},
+		{
+			name:    "hard link",
+			entries: []tarEntry{{name: "h", typeflag: tar.TypeLink, linkname: "/etc/passwd"}},
+			err:     constants.ErrUnsafeArchive,
+		},
+		{
+			name:    "absolute symlink",
+			entries: []tarEntry{{name: "l", typeflag: tar.TypeSymlink, linkname: "/etc/passwd"}},
+			err:     constants.ErrUnsafeArchive,
+		},
+		{
+			name:    "symlink out of the root",
+			entries: []tarEntry{{name: "d/l", typeflag: tar.TypeSymlink, linkname: "../../x"}},
+			err:     constants.ErrUnsafeArchive,
+		},
+		{
+			name:    "symlink into the .git dir",
+			entries: []tarEntry{{name: "l", typeflag: tar.TypeSymlink, linkname: ".git/config"}},
+			err:     constants.ErrUnsafeArchive,
+		},
+		{
+			name:    "file through a symlink",
+			entries: []tarEntry{{name: "d", typeflag: tar.TypeSymlink, linkname: ".."}, {name: "d/x", body: "x"}},
+			err:     constants.ErrUnsafeArchive,
+		},
+		{
+			name:    "symlink through a symlink",
+			entries: []tarEntry{{name: "d/l", typeflag: tar.TypeSymlink, linkname: ".."}, {name: "e", typeflag: tar.TypeSymlink, linkname: "d/l/.."}},
+			err:     constants.ErrUnsafeArchive,
+		},
+		{
+			name:    "symlink through a later symlink",
+			entries: []tarEntry{{name: "e", typeflag: tar.TypeSymlink, linkname: "d/l/.."}, {name: "d/l", typeflag: tar.TypeSymlink, linkname: ".."}},
+			err:     constants.ErrUnsafeArchive,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			root, err := ioutil.TempDir("", "gopx-extract-test-")
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer os.RemoveAll(root)
+
+			dst := filepath.Join(root, "pkg")
+			err = os.Mkdir(dst, 0755)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			err = extractPackageData(dst, buildTar(t, c.entries), constants.ArchiveFormatTar)
+			if errors.Cause(err) != c.err {
+				t.Fatalf("got error %v, want %v", err, c.err)
+			}
+
+			files, err := ioutil.ReadDir(root)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(files) != 1 {
+				t.Fatalf("extraction escaped the package root, found %d entries next to it", len(files)-1)
+			}
+		})
+	}
+}
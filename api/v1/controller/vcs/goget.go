@@ -0,0 +1,52 @@
+package vcs
+
+import (
+	"fmt"
+	"strings"
+
+	"gopx.io/gopx-vcs-api/api/v1/constants"
+	"gopx.io/gopx-vcs-api/api/v1/types"
+	"gopx.io/gopx-vcs-api/pkg/config"
+)
+
+// GoImportMeta resolves the go-import and go-source meta tags of the vanity
+// import path requested at reqPath, which is the import path without the
+// host. The packages inside of the package repo resolve to the repo root.
+func GoImportMeta(reqPath string) (meta *types.GoImportMeta, err error) {
+	prefix := strings.TrimSuffix(config.VCS.ImportPathPrefix, "/")
+
+	// Only the path part of the prefix is present in the request path.
+	prefixPath := ""
+	if i := strings.Index(prefix, "/"); i >= 0 {
+		prefixPath = prefix[i+1:] + "/"
+	}
+
+	p := strings.Trim(reqPath, "/")
+	if !strings.HasPrefix(p, prefixPath) {
+		err = constants.ErrPackageNotFound
+		return
+	}
+
+	pkgName := strings.SplitN(strings.TrimPrefix(p, prefixPath), "/", 2)[0]
+	if pkgName == "" {
+		err = constants.ErrPackageNotFound
+		return
+	}
+
+	_, err = exportedPackageRepoPath(pkgName)
+	if err != nil {
+		return
+	}
+
+	home := fmt.Sprintf("%s/%s", strings.TrimSuffix(config.VCS.BrowseBaseURL, "/"), pkgName)
+	meta = &types.GoImportMeta{
+		ImportPrefix: fmt.Sprintf("%s/%s", prefix, pkgName),
+		VCS:          "git",
+		RepoURL:      fmt.Sprintf("%s/%s.git", strings.TrimSuffix(config.VCS.GitBaseURL, "/"), pkgName),
+		Home:         home,
+		Directory:    home + "/tree{/dir}",
+		File:         home + "/blob{/dir}/{file}#L{line}",
+	}
+
+	return
+}
@@ -0,0 +1,396 @@
+package vcs
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"golang.org/x/mod/module"
+	modsemver "golang.org/x/mod/semver"
+	modzip "golang.org/x/mod/zip"
+	git "gopkg.in/src-d/go-git.v4"
+	"gopkg.in/src-d/go-git.v4/plumbing/object"
+	"gopx.io/gopx-vcs-api/api/v1/constants"
+	"gopx.io/gopx-vcs-api/api/v1/types"
+	"gopx.io/gopx-vcs-api/pkg/config"
+)
+
+// goModule represents an exported package repo served as a Go module. The
+// module path is the import path prefix followed by the package name, and
+// the major version suffix for v2 and above.
+type goModule struct {
+	path      string
+	pathMajor string
+	pkgName   string
+	repo      *git.Repository
+}
+
+// goModuleVersion represents a release tag of a package repo served as a
+// version of a Go module.
+type goModuleVersion struct {
+	version string
+	tag     *object.Tag
+	commit  *object.Commit
+	goMod   []byte
+}
+
+// GoModuleZip represents the module zip of a Go module version which is
+// streamed straight out of the package repo.
+type GoModuleZip struct {
+	FileName string
+	ModTime  time.Time
+	version  module.Version
+	files    []modzip.File
+}
+
+// GoModuleVersions returns the versions of a Go module. The module path is
+// expected in its escaped form as used by the module proxy protocol.
+func GoModuleVersions(escModPath string) (versions []string, err error) {
+	m, err := openGoModule(escModPath)
+	if err != nil {
+		return
+	}
+
+	mvs, err := m.versions()
+	if err != nil {
+		return
+	}
+
+	versions = make([]string, 0, len(mvs))
+	for _, mv := range mvs {
+		versions = append(versions, mv.version)
+	}
+
+	return
+}
+
+// GoModuleLatest returns the info of the latest version of a Go module. The
+// releases are preferred over the prereleases, and the +incompatible versions
+// are skipped if the latest compatible version has a go.mod, the same way the
+// go command picks the latest version out of the version list.
+func GoModuleLatest(escModPath string) (info *types.GoModuleInfo, err error) {
+	m, err := openGoModule(escModPath)
+	if err != nil {
+		return
+	}
+
+	mvs, err := m.versions()
+	if err != nil {
+		return
+	}
+
+	skipIncompatible := false
+	for _, mv := range mvs {
+		if !mv.incompatible() {
+			skipIncompatible = mv.goMod != nil
+			break
+		}
+	}
+
+	var latest *goModuleVersion
+	for _, mv := range mvs {
+		if skipIncompatible && mv.incompatible() {
+			continue
+		}
+		if latest == nil || (modsemver.Prerelease(latest.version) != "" && modsemver.Prerelease(mv.version) == "") {
+			latest = mv
+		}
+	}
+
+	if latest == nil {
+		err = constants.ErrVersionNotFound
+		return
+	}
+
+	info = latest.info()
+	return
+}
+
+// GoModuleVersionInfo returns the info of a Go module version.
+func GoModuleVersionInfo(escModPath, escVersion string) (info *types.GoModuleInfo, err error) {
+	_, mv, err := openGoModuleVersion(escModPath, escVersion)
+	if err != nil {
+		return
+	}
+
+	info = mv.info()
+	return
+}
+
+// GoModuleVersionMod returns the go.mod file of a Go module version. A go.mod
+// declaring only the module path is synthesized if the release has none.
+func GoModuleVersionMod(escModPath, escVersion string) (goMod []byte, err error) {
+	m, mv, err := openGoModuleVersion(escModPath, escVersion)
+	if err != nil {
+		return
+	}
+
+	goMod = mv.goMod
+	if goMod == nil {
+		goMod = []byte(fmt.Sprintf("module %s\n", m.path))
+	}
+
+	return
+}
+
+// GoModuleVersionZip resolves the module zip of a Go module version.
+func GoModuleVersionZip(escModPath, escVersion string) (mz *GoModuleZip, err error) {
+	m, mv, err := openGoModuleVersion(escModPath, escVersion)
+	if err != nil {
+		return
+	}
+
+	files, err := commitModuleZipFiles(mv.commit)
+	if err != nil {
+		err = errors.Wrapf(err, "Couldn't read the release files [%s]", m.pkgName)
+		return
+	}
+
+	// The releases published before the module validation can have files
+	// which aren't allowed in a module zip, those are reported as gone.
+	_, err = modzip.CheckFiles(files)
+	if err != nil {
+		err = errors.Wrapf(constants.ErrInvalidModuleZip, "Release %s can't be served as a module zip [%s]: %s", mv.tag.Name, m.pkgName, err)
+		return
+	}
+
+	mz = &GoModuleZip{
+		FileName: fmt.Sprintf("%s.zip", mv.version),
+		ModTime:  mv.commit.Committer.When,
+		version:  module.Version{Path: m.path, Version: mv.version},
+		files:    files,
+	}
+
+	return
+}
+
+// Write writes the module zip to w.
+func (mz *GoModuleZip) Write(w io.Writer) error {
+	return modzip.Create(w, mz.version, mz.files)
+}
+
+// openGoModule resolves the exported package repo of the escaped module path.
+func openGoModule(escModPath string) (m *goModule, err error) {
+	modPath, err := module.UnescapePath(escModPath)
+	if err != nil {
+		err = constants.ErrPackageNotFound
+		return
+	}
+
+	prefix := strings.TrimSuffix(config.VCS.ImportPathPrefix, "/") + "/"
+	if !strings.HasPrefix(modPath, prefix) {
+		err = constants.ErrPackageNotFound
+		return
+	}
+
+	pkgName, pathMajor, ok := module.SplitPathVersion(strings.TrimPrefix(modPath, prefix))
+	if !ok || pkgName == "" || strings.Contains(pkgName, "/") {
+		err = constants.ErrPackageNotFound
+		return
+	}
+
+	rPath, err := exportedPackageRepoPath(pkgName)
+	if err != nil {
+		return
+	}
+
+	repo, err := git.PlainOpen(rPath)
+	if err != nil {
+		err = errors.Wrapf(err, "Package repo couldn't be opened [%s]", pkgName)
+		return
+	}
+
+	m = &goModule{
+		path:      modPath,
+		pathMajor: pathMajor,
+		pkgName:   pkgName,
+		repo:      repo,
+	}
+
+	return
+}
+
+// openGoModuleVersion resolves the release of the escaped module version.
+func openGoModuleVersion(escModPath, escVersion string) (m *goModule, mv *goModuleVersion, err error) {
+	m, err = openGoModule(escModPath)
+	if err != nil {
+		return
+	}
+
+	version, err := module.UnescapeVersion(escVersion)
+	if err != nil || version != module.CanonicalVersion(version) {
+		err = constants.ErrVersionNotFound
+		return
+	}
+
+	// The release tags never carry the +incompatible build metadata.
+	tag, err := repoVersionTag(m.repo, modsemver.Canonical(version))
+	if err != nil {
+		err = errors.Wrapf(err, "Failed to resolve the release tag of %s [%s]", version, m.pkgName)
+		return
+	}
+
+	mv, ok, err := m.moduleVersion(tag)
+	if err != nil {
+		return
+	}
+
+	if !ok || mv.version != version {
+		err = constants.ErrVersionNotFound
+		return
+	}
+
+	return
+}
+
+// versions returns the module versions sorted from the newest to the oldest.
+func (m *goModule) versions() (mvs []*goModuleVersion, err error) {
+	tags, err := packageRepoTags(m.repo)
+	if err != nil {
+		err = errors.Wrapf(err, "Couldn't read release tags [%s]", m.pkgName)
+		return
+	}
+
+	for _, t := range tags {
+		mv, ok, err := m.moduleVersion(t.tag)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			mvs = append(mvs, mv)
+		}
+	}
+
+	return
+}
+
+// moduleVersion maps the release tag to a version of the module. It reports
+// false if the release belongs to a module path of another major version.
+func (m *goModule) moduleVersion(tag *object.Tag) (mv *goModuleVersion, ok bool, err error) {
+	commit, err := tag.Commit()
+	if err != nil {
+		err = errors.Wrapf(err, "Couldn't access tagged commit %s [%s]", tag.Name, m.pkgName)
+		return
+	}
+
+	goMod, err := commitGoMod(commit)
+	if err != nil {
+		err = errors.Wrapf(err, "Couldn't read go.mod of %s [%s]", tag.Name, m.pkgName)
+		return
+	}
+
+	version := tag.Name
+	if m.pathMajor == "" && modsemver.Major(version) != "v0" && modsemver.Major(version) != "v1" {
+		if goMod != nil {
+			return
+		}
+		version += "+incompatible"
+	}
+
+	if module.Check(m.path, version) != nil {
+		return
+	}
+
+	mv = &goModuleVersion{
+		version: version,
+		tag:     tag,
+		commit:  commit,
+		goMod:   goMod,
+	}
+	ok = true
+
+	return
+}
+
+func (mv *goModuleVersion) info() *types.GoModuleInfo {
+	return &types.GoModuleInfo{
+		Version: mv.version,
+		Time:    mv.tag.Tagger.When.UTC(),
+	}
+}
+
+func (mv *goModuleVersion) incompatible() bool {
+	return strings.HasSuffix(mv.version, "+incompatible")
+}
+
+// commitGoMod returns the content of the go.mod file at the root of the
+// commit, or nil if there is none.
+func commitGoMod(commit *object.Commit) (goMod []byte, err error) {
+	f, err := commit.File("go.mod")
+	if err != nil {
+		if err == object.ErrFileNotFound {
+			err = nil
+		}
+		return
+	}
+
+	r, err := f.Reader()
+	if err != nil {
+		return
+	}
+	defer r.Close()
+
+	goMod, err = ioutil.ReadAll(r)
+	return
+}
+
+// commitModuleZipFiles returns the files of the commit as the module zip
+// files.
+func commitModuleZipFiles(commit *object.Commit) (files []modzip.File, err error) {
+	fileIter, err := commit.Files()
+	if err != nil {
+		err = errors.Wrap(err, "Couldn't access commit files")
+		return
+	}
+
+	err = fileIter.ForEach(func(f *object.File) error {
+		files = append(files, moduleZipFile{f})
+		return nil
+	})
+	if err != nil {
+		err = errors.Wrap(err, "Couldn't iterate over commit files")
+		return
+	}
+
+	return
+}
+
+// moduleZipFile adapts a file of the release tree to the module zip file.
+type moduleZipFile struct {
+	f *object.File
+}
+
+func (mzf moduleZipFile) Path() string {
+	return mzf.f.Name
+}
+
+func (mzf moduleZipFile) Lstat() (os.FileInfo, error) {
+	return moduleZipFileInfo{mzf.f}, nil
+}
+
+func (mzf moduleZipFile) Open() (io.ReadCloser, error) {
+	return mzf.f.Reader()
+}
+
+type moduleZipFileInfo struct {
+	f *object.File
+}
+
+func (fi moduleZipFileInfo) Name() string       { return path.Base(fi.f.Name) }
+func (fi moduleZipFileInfo) Size() int64        { return fi.f.Size }
+func (fi moduleZipFileInfo) ModTime() time.Time { return time.Time{} }
+func (fi moduleZipFileInfo) IsDir() bool        { return false }
+func (fi moduleZipFileInfo) Sys() interface{}   { return nil }
+
+func (fi moduleZipFileInfo) Mode() os.FileMode {
+	mode, err := fi.f.Mode.ToOSFileMode()
+	if err != nil {
+		return os.ModeIrregular
+	}
+	return mode
+}
@@ -0,0 +1,69 @@
+package vcs
+
+import (
+	"github.com/Masterminds/semver"
+	"github.com/pkg/errors"
+	git "gopkg.in/src-d/go-git.v4"
+	"gopkg.in/src-d/go-git.v4/plumbing/object"
+	"gopx.io/gopx-vcs-api/api/v1/constants"
+	"gopx.io/gopx-vcs-api/api/v1/types"
+)
+
+// retriedPublishTag returns the release tag of the already published
+// version, the uploaded content is compared with it by retriedPublishVersion.
+func retriedPublishTag(rPath string, meta *types.PackageMeta) (tag *object.Tag, err error) {
+	repo, err := git.PlainOpen(rPath)
+	if err != nil {
+		err = errors.Wrapf(err, "Package repo couldn't be opened [%s]", meta.Name)
+		return
+	}
+
+	tag, err = repoVersionTag(repo, meta.Version)
+	if err != nil {
+		err = errors.Wrapf(err, "Failed to resolve the release tag of %s [%s]", meta.Version, meta.Name)
+		return
+	}
+
+	return
+}
+
+// retriedPublishVersion compares the tree of the publish commit with the one
+// of the published release. Different trees are reported with
+// ErrVersionConflict along with both tree hashes. The same tree is answered
+// with the published version if the publish is a retry of the request which
+// published it, i.e. it has the same idempotency key, otherwise the version
+// is reported as existing with ErrVersionExists.
+func retriedPublishVersion(tag *object.Tag, meta *types.PackageMeta, commit *object.Commit) (pkgVer *types.PackageVersion, err error) {
+	tagCommit, err := tag.Commit()
+	if err != nil {
+		err = errors.Wrapf(err, "Couldn't access tagged commit %s", tag.Name)
+		return
+	}
+
+	if tagCommit.TreeHash != commit.TreeHash {
+		err = errors.Wrapf(
+			constants.ErrVersionConflict,
+			"Version %s was published with tree %s, the uploaded tree is %s",
+			tag.Name, tagCommit.TreeHash, commit.TreeHash,
+		)
+		return
+	}
+
+	key := tagMessageTrailer(tag.Message, constants.TagTrailerIdempotencyKey)
+	if meta.IdempotencyKey == "" || key != meta.IdempotencyKey {
+		err = errors.Wrapf(
+			constants.ErrVersionExists,
+			"Version %s was published with the same tree %s by another request",
+			tag.Name, tagCommit.TreeHash,
+		)
+		return
+	}
+
+	tagVer, err := semver.NewVersion(tag.Name)
+	if err != nil {
+		return
+	}
+
+	pkgVer = (&packageTag{version: tagVer, tag: tag}).packageVersion()
+	return
+}
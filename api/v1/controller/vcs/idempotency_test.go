@@ -0,0 +1,109 @@
+package vcs
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/pkg/errors"
+	git "gopkg.in/src-d/go-git.v4"
+	"gopkg.in/src-d/go-git.v4/plumbing/object"
+	"gopx.io/gopx-vcs-api/api/v1/constants"
+	"gopx.io/gopx-vcs-api/api/v1/types"
+)
+
+func TestRetriedPublishVersion(t *testing.T) {
+	rPath := newTaggedRepo(t)
+	defer os.RemoveAll(rPath)
+
+	repo, err := git.PlainOpen(rPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wt, err := repo.Worktree()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	commit := func(content string) *object.Commit {
+		err := ioutil.WriteFile(filepath.Join(rPath, "version"), []byte(content), 0644)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, err = wt.Add("version")
+		if err != nil {
+			t.Fatal(err)
+		}
+		hash, err := wt.Commit(content, &git.CommitOptions{Author: vcsRepoTaggerSignature()})
+		if err != nil {
+			t.Fatal(err)
+		}
+		c, err := repo.CommitObject(hash)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return c
+	}
+
+	published := commit("1.0.0")
+	_, err = vcsRepoCreateTag(
+		repo,
+		"v1.0.0",
+		vcsRepoTaggerSignature(),
+		vcsRepoTagMessage("v1.0.0", "", tagTrailer{constants.TagTrailerIdempotencyKey, "key-1"}),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	same := commit("1.0.0")
+	other := commit("1.0.1")
+	if same.Hash == published.Hash || same.TreeHash != published.TreeHash {
+		t.Fatal("the retried commit must be a new commit of the published tree")
+	}
+
+	cases := []struct {
+		name   string
+		key    string
+		commit *object.Commit
+		err    error
+	}{
+		{name: "matching key", key: "key-1", commit: same},
+		{name: "different key", key: "key-2", commit: same, err: constants.ErrVersionExists},
+		{name: "no key", commit: same, err: constants.ErrVersionExists},
+		{name: "same key, different content", key: "key-1", commit: other, err: constants.ErrVersionConflict},
+		{name: "different key, different content", key: "key-2", commit: other, err: constants.ErrVersionConflict},
+		{name: "no key, different content", commit: other, err: constants.ErrVersionConflict},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			meta := &types.PackageMeta{Name: "foo", Version: "1.0.0", IdempotencyKey: c.key}
+
+			tag, err := retriedPublishTag(rPath, meta)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			pkgVer, err := retriedPublishVersion(tag, meta, c.commit)
+			if errors.Cause(err) != c.err {
+				t.Fatalf("got error %v, want %v", err, c.err)
+			}
+			if err != nil {
+				if c.err == constants.ErrVersionConflict {
+					for _, h := range []string{published.TreeHash.String(), c.commit.TreeHash.String()} {
+						if !strings.Contains(err.Error(), h) {
+							t.Errorf("error %q doesn't contain tree hash %s", err, h)
+						}
+					}
+				}
+				return
+			}
+			if pkgVer == nil || pkgVer.Version != "1.0.0" {
+				t.Errorf("got version %+v, want the published version 1.0.0", pkgVer)
+			}
+		})
+	}
+}
@@ -0,0 +1,45 @@
+package vcs
+
+import (
+	"sync"
+)
+
+// packageLock serializes the changes of a single package. The refs count the
+// holders and waiters, the lock is dropped from the set when it reaches zero.
+type packageLock struct {
+	sync.Mutex
+	refs int
+}
+
+// packageLocks holds the locks of the packages being changed, keyed by the
+// package name so that the public and the private repo of a name share one.
+var packageLocks = struct {
+	sync.Mutex
+	locks map[string]*packageLock
+}{locks: map[string]*packageLock{}}
+
+// lockPackage blocks until no other publish of the package is running and
+// returns the function releasing the lock.
+func lockPackage(pkgName string) (unlock func()) {
+	packageLocks.Lock()
+	l, ok := packageLocks.locks[pkgName]
+	if !ok {
+		l = &packageLock{}
+		packageLocks.locks[pkgName] = l
+	}
+	l.refs++
+	packageLocks.Unlock()
+
+	l.Lock()
+
+	return func() {
+		l.Unlock()
+
+		packageLocks.Lock()
+		l.refs--
+		if l.refs == 0 {
+			delete(packageLocks.locks, pkgName)
+		}
+		packageLocks.Unlock()
+	}
+}
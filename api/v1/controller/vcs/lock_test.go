@@ -0,0 +1,40 @@
+package vcs
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLockPackage(t *testing.T) {
+	unlock := lockPackage("foo")
+
+	locked := make(chan struct{})
+	go func() {
+		unlockAgain := lockPackage("foo")
+		close(locked)
+		unlockAgain()
+	}()
+
+	// Other packages aren't held up by the lock of foo.
+	lockPackage("bar")()
+
+	select {
+	case <-locked:
+		t.Fatal("the package was locked twice")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	unlock()
+
+	select {
+	case <-locked:
+	case <-time.After(time.Second):
+		t.Fatal("the package wasn't locked after it was released")
+	}
+
+	packageLocks.Lock()
+	defer packageLocks.Unlock()
+	if n := len(packageLocks.locks); n != 0 {
+		t.Errorf("got %d locks left after release, want none", n)
+	}
+}
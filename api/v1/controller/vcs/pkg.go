@@ -6,14 +6,18 @@ import (
 	"io/ioutil"
 	"os"
 	"path/filepath"
+	"sort"
+	"strings"
 	"time"
 
 	"github.com/Masterminds/semver"
 	"github.com/pkg/errors"
 	git "gopkg.in/src-d/go-git.v4"
 	"gopkg.in/src-d/go-git.v4/config"
+	"gopkg.in/src-d/go-git.v4/plumbing/object"
 	"gopkg.in/src-d/go-git.v4/plumbing/transport"
 	"gopx.io/gopx-common/fs"
+	"gopx.io/gopx-vcs-api/api/v1/constants"
 	"gopx.io/gopx-vcs-api/api/v1/controller/helper"
 	"gopx.io/gopx-vcs-api/api/v1/types"
 )
@@ -22,28 +26,167 @@ import (
 // Note: Assume the package meta and owner info provided in
 // http request (from gopx-api service) is valid,
 // so doesn't need to sanitize it again.
-func RegisterPublicPackage(meta *types.PackageMeta, data io.Reader) (err error) {
+func RegisterPublicPackage(meta *types.PackageMeta, data io.Reader) (pkgVer *types.PackageVersion, err error) {
+	pkgName := meta.Name
+
+	// A retry sent while the first publish is still running waits for it,
+	// and then finds the published version.
+	unlock := lockPackage(pkgName)
+	defer unlock()
+
+	privPath, err := privatePackageRepoPath(pkgName)
+	if err != nil {
+		return
+	}
+
+	privExists, err := fs.Exists(privPath)
+	if err != nil {
+		err = errors.Wrapf(err, "Failed to check private package path existence [%s]", pkgName)
+		return
+	}
+
+	if privExists {
+		err = errors.Wrapf(constants.ErrPackageExists, "Package name is taken by a private package [%s]", pkgName)
+		return
+	}
+
+	rPath, err := packageRepoPath(pkgName)
+	if err != nil {
+		return
+	}
+
+	pkgVer, err = registerPackage(meta, data, rPath, true)
+	if err != nil {
+		return
+	}
+
+	err = exportPackageRepo(pkgName)
+	if err != nil {
+		err = errors.Wrapf(err, "Unable to export package repo [%s]", pkgName)
+		return
+	}
+
+	return
+}
+
+// RegisterPrivatePackage registers a package to the vcs registry as private.
+// Private package repos are stored under a separate root and never exported.
+// Note: Assume the package meta and owner info provided in
+// http request (from gopx-api service) is valid,
+// so doesn't need to sanitize it again.
+func RegisterPrivatePackage(meta *types.PackageMeta, data io.Reader) (pkgVer *types.PackageVersion, err error) {
+	pkgName := meta.Name
+
+	// A retry sent while the first publish is still running waits for it,
+	// and then finds the published version.
+	unlock := lockPackage(pkgName)
+	defer unlock()
+
+	pubPath, err := packageRepoPath(pkgName)
+	if err != nil {
+		return
+	}
+
+	pubExists, err := fs.Exists(pubPath)
+	if err != nil {
+		err = errors.Wrapf(err, "Failed to check public package path existence [%s]", pkgName)
+		return
+	}
+
+	if pubExists {
+		err = errors.Wrapf(constants.ErrPackageExists, "Package name is taken by a public package [%s]", pkgName)
+		return
+	}
+
+	rPath, err := privatePackageRepoPath(pkgName)
+	if err != nil {
+		return
+	}
+
+	pkgVer, err = registerPackage(meta, data, rPath, false)
+
+	return
+}
+
+// registerPackage commits the package data as a new version into the
+// package repo located at rPath and tags the release along with its go.sum
+// hashes.
+func registerPackage(meta *types.PackageMeta, data io.Reader, rPath string, exported bool) (pkgVer *types.PackageVersion, err error) {
 	pkgName := meta.Name
 	pkgVersion := meta.Version
 	owner := &meta.Owner
 
-	err = resolvePackageRepo(pkgName)
+	// The version and notes errors are kept unwrapped, they're reported to
+	// the client.
+	err = validatePackageVersion(pkgVersion)
+	if err != nil {
+		return
+	}
+
+	notes, err := normalizeReleaseNotes(meta.ReleaseNotes)
+	if err != nil {
+		return
+	}
+
+	repoExists, err := fs.Exists(rPath)
+	if err != nil {
+		err = errors.Wrapf(err, "Failed to check package repo existence [%s]", pkgName)
+		return
+	}
+
+	err = resolvePackageRepo(rPath, exported)
 	if err != nil {
 		err = errors.Wrapf(err, "Failed to resolve the package [%s]", pkgName)
 		return
 	}
 
-	verExists, err := packageVersionExists(pkgName, pkgVersion)
+	// A rejected first version must not leave an empty repo behind, it would
+	// be taken as a corrupted one on the next publish.
+	if !repoExists {
+		defer func() {
+			if err != nil {
+				os.RemoveAll(rPath)
+			}
+		}()
+	}
+
+	verExists, err := repoVersionExists(rPath, pkgVersion)
 	if err != nil {
 		err = errors.Wrapf(err, "Failed to check version exists or not [%s]", pkgName)
 		return
 	}
 
+	// An existing version is compared with the uploaded content once it's
+	// committed, a retry of the request which published it is answered with
+	// the published version.
+	var retryTag *object.Tag
 	if verExists {
-		err = errors.Errorf("Package version already exists %s [%s]", meta.Version, pkgName)
+		retryTag, err = retriedPublishTag(rPath, meta)
+		if err != nil {
+			return
+		}
+	}
+
+	verDeleted, err := isTombstonedVersion(rPath, pkgVersion)
+	if err != nil {
+		err = errors.Wrapf(err, "Failed to check version is deleted or not [%s]", pkgName)
+		return
+	}
+
+	if verDeleted {
+		err = errors.Wrapf(constants.ErrVersionDeleted, "Package version %s can't be published again [%s]", meta.Version, pkgName)
 		return
 	}
 
+	// The policy errors are kept unwrapped, they're reported to the client.
+	var latest bool
+	if retryTag == nil {
+		latest, err = checkPublishPolicy(rPath, pkgVersion)
+		if err != nil {
+			return
+		}
+	}
+
 	opsDir, err := tempPackageRepoOpsDir(pkgName)
 	if err != nil {
 		err = errors.Wrapf(err, "Unable to create new temp dir for repo operations [%s]", pkgName)
@@ -51,11 +194,7 @@ func RegisterPublicPackage(meta *types.PackageMeta, data io.Reader) (err error)
 	}
 	defer os.RemoveAll(opsDir)
 
-	cloneOpt, err := packageRepoCloneOptions(pkgName)
-	if err != nil {
-		err = errors.Wrapf(err, "Failed to create package repo clone options [%s]", pkgName)
-		return
-	}
+	cloneOpt := repoCloneOptions(rPath)
 
 	repo, err := git.PlainClone(opsDir, false, cloneOpt)
 	if err != nil && err != transport.ErrEmptyRemoteRepository {
@@ -86,12 +225,18 @@ func RegisterPublicPackage(meta *types.PackageMeta, data io.Reader) (err error)
 		return
 	}
 
-	_, err = fs.DecompressTarGZ(opsDir, data)
+	err = extractPackageData(opsDir, data, meta.Format)
 	if err != nil {
 		err = errors.Wrapf(err, "Couldn't extract package data into worktree [%s]", pkgName)
 		return
 	}
 
+	// The module errors are kept unwrapped, they're reported to the client.
+	err = validatePackageModule(opsDir, meta)
+	if err != nil {
+		return
+	}
+
 	files, err = ioutil.ReadDir(opsDir)
 	if err != nil {
 		err = errors.Wrapf(err, "Unable to read package temp operaion dir [%s]", pkgName)
@@ -115,7 +260,7 @@ func RegisterPublicPackage(meta *types.PackageMeta, data io.Reader) (err error)
 		return
 	}
 
-	_, err = wt.Commit(
+	commitHash, err := wt.Commit(
 		vcsRepoCommitMessage(pkgTagName),
 		&git.CommitOptions{
 			All:       true,
@@ -128,88 +273,93 @@ func RegisterPublicPackage(meta *types.PackageMeta, data io.Reader) (err error)
 		return
 	}
 
-	err = vcsRepoCreateTag(
+	commit, err := repo.CommitObject(commitHash)
+	if err != nil {
+		err = errors.Wrapf(err, "Couldn't access the package commit [%s]", pkgName)
+		return
+	}
+
+	// The retry errors are kept unwrapped, they're reported to the client.
+	if retryTag != nil {
+		pkgVer, err = retriedPublishVersion(retryTag, meta, commit)
+		return
+	}
+
+	modPath, err := packageModulePath(pkgName, pkgVersion)
+	if err != nil {
+		return
+	}
+
+	sum, goModSum, err := commitModuleSums(modPath, pkgTagName, commit)
+	if err != nil {
+		err = errors.Wrapf(err, "Failed to compute the go.sum hashes [%s]", pkgName)
+		return
+	}
+
+	trailers := []tagTrailer{
+		{constants.TagTrailerGoSum, sum},
+		{constants.TagTrailerGoModSum, goModSum},
+	}
+	if meta.IdempotencyKey != "" {
+		trailers = append(trailers, tagTrailer{constants.TagTrailerIdempotencyKey, meta.IdempotencyKey})
+	}
+
+	tag, err := vcsRepoCreateTag(
 		repo,
 		pkgTagName,
 		vcsRepoTaggerSignature(),
-		vcsRepoTagMessage(pkgTagName),
+		vcsRepoTagMessage(pkgTagName, notes, trailers...),
 	)
 	if err != nil {
 		err = errors.Wrapf(err, "Unable to create tag [%s]", pkgName)
 		return
 	}
 
-	tagRefSpec := config.RefSpec(fmt.Sprintf("refs/tags/%s:refs/tags/%s", pkgTagName, pkgTagName))
-	masterRefSpec := config.RefSpec("+refs/heads/master:refs/heads/master")
+	// The master follows the latest version, a backport only adds its tag.
+	refSpecs := []config.RefSpec{config.RefSpec(fmt.Sprintf("refs/tags/%s:refs/tags/%s", pkgTagName, pkgTagName))}
+	if latest {
+		refSpecs = append(refSpecs, config.RefSpec("+refs/heads/master:refs/heads/master"))
+	}
 	err = repo.Push(&git.PushOptions{
 		RemoteName: "origin",
-		RefSpecs:   []config.RefSpec{tagRefSpec, masterRefSpec},
+		RefSpecs:   refSpecs,
 	})
 	if err != nil && err != git.NoErrAlreadyUpToDate {
 		err = errors.Wrapf(err, "Unable to push package data to the repo [%s]", pkgName)
 		return
 	}
 
-	err = exportPackageRepo(pkgName)
+	tagVer, err := semver.NewVersion(tag.Name)
 	if err != nil {
-		err = errors.Wrapf(err, "Unable to export package repo [%s]", pkgName)
 		return
 	}
 
-	return nil
-}
-
-// RegisterPrivatePackage registers a package to the vcs registry as private.
-// Note: Assume the package meta and owner info provided in
-// http request (from gopx-api service) is valid,
-// so doesn't need to sanitize it again.
-func RegisterPrivatePackage(meta *types.PackageMeta, data io.Reader) (err error) {
-	return errors.New("Private package is not supported")
+	pkgVer = (&packageTag{version: tagVer, tag: tag}).packageVersion()
+	return
 }
 
-func packageVersionExists(pkgName, version string) (ok bool, err error) {
+func repoVersionExists(rPath, version string) (ok bool, err error) {
 	v, err := semver.NewVersion(version)
 	if err != nil {
 		return
 	}
 
-	rPath, err := packageRepoPath(pkgName)
-	if err != nil {
-		return
-	}
-
 	repo, err := git.PlainOpen(rPath)
 	if err != nil {
-		err = errors.Wrapf(err, "Package repo couldn't be opened [%s]", pkgName)
+		err = errors.Wrapf(err, "Package repo couldn't be opened [%s]", extractPkgName(rPath))
 		return
 	}
 
-	tagIter, err := repo.TagObjects()
+	tags, err := packageRepoTags(repo)
 	if err != nil {
-		err = errors.Wrapf(err, "Couldn't access tag Objects [%s]", pkgName)
+		err = errors.Wrapf(err, "Couldn't read release tags [%s]", extractPkgName(rPath))
 		return
 	}
 
-	for {
-		tag, err := tagIter.Next()
-		if err != nil {
-			if err == io.EOF {
-				break
-			} else {
-				err = errors.Wrapf(err, "Couldn't iterate over tag Objects [%s]", pkgName)
-				return false, err
-			}
-		}
-
-		tagVer, err := semver.NewVersion(tag.Name)
-		if err != nil {
-			return false, err
-		}
-
-		if v.Equal(tagVer) {
+	for _, t := range tags {
+		if v.Equal(t.version) {
 			return true, nil
 		}
-
 	}
 
 	return false, nil
@@ -249,9 +399,163 @@ func PackageExists(pkgName string) (ok bool, err error) {
 	return
 }
 
+// ListPackages returns a page of the visible packages whose names start with
+// the prefix along with the total number of matched packages.
+func ListPackages(prefix string, page, perPage int) (pkgs []*types.PackageListItem, total int, err error) {
+	root := packageRepoRoot()
+
+	files, err := ioutil.ReadDir(root)
+	if err != nil {
+		if os.IsNotExist(err) {
+			err = nil
+			pkgs = []*types.PackageListItem{}
+			return
+		}
+		err = errors.Wrap(err, "Unable to read the repo root dir")
+		return
+	}
+
+	names := []string{}
+	for _, f := range files {
+		if !f.IsDir() || !isPackageRepoName(f.Name()) {
+			continue
+		}
+
+		rPath := filepath.Join(root, f.Name())
+		visible, err := isVisibleRepo(rPath)
+		if err != nil {
+			err = errors.Wrapf(err, "Failed to check whether the package is visible or not [%s]", extractPkgName(rPath))
+			return nil, 0, err
+		}
+
+		if !visible {
+			continue
+		}
+
+		pkgName := extractPkgName(rPath)
+		if strings.HasPrefix(pkgName, prefix) {
+			names = append(names, pkgName)
+		}
+	}
+
+	sort.Strings(names)
+	total = len(names)
+
+	start := (page - 1) * perPage
+	if start > total {
+		start = total
+	}
+	end := start + perPage
+	if end > total {
+		end = total
+	}
+
+	pkgs = make([]*types.PackageListItem, 0, end-start)
+	for _, name := range names[start:end] {
+		pkgs = append(pkgs, &types.PackageListItem{Name: name})
+	}
+
+	return
+}
+
+// FindPackage checks whether a public or a private package exists
+// with the name and returns its type.
+func FindPackage(pkgName string) (pkgType types.PackageType, ok bool, err error) {
+	ok, err = PackageExists(pkgName)
+	if err != nil || ok {
+		pkgType = types.PackageTypePublic
+		return
+	}
+
+	privPath, err := privatePackageRepoPath(pkgName)
+	if err != nil {
+		return
+	}
+
+	ok, err = fs.Exists(privPath)
+	if err != nil {
+		err = errors.Wrapf(err, "Failed to check private package path existence [%s]", pkgName)
+		return
+	}
+
+	pkgType = types.PackageTypePrivate
+
+	return
+}
+
+// PackageSummary returns the overview of a package including its
+// latest versions, publish times, repo size and export state.
+func PackageSummary(pkgName string) (summary *types.PackageSummary, err error) {
+	rPath, err := lookupPackageRepoPath(pkgName)
+	if err != nil {
+		return
+	}
+
+	exists, err := fs.Exists(rPath)
+	if err != nil {
+		err = errors.Wrapf(err, "Failed to check package path existence [%s]", pkgName)
+		return
+	}
+
+	if !exists {
+		err = constants.ErrPackageNotFound
+		return
+	}
+
+	repo, err := git.PlainOpen(rPath)
+	if err != nil {
+		if err == git.ErrRepositoryNotExists {
+			err = constants.ErrPackageNotFound
+			return
+		}
+		err = errors.Wrapf(err, "Package repo couldn't be opened [%s]", pkgName)
+		return
+	}
+
+	tags, err := packageRepoTags(repo)
+	if err != nil {
+		err = errors.Wrapf(err, "Couldn't read release tags [%s]", pkgName)
+		return
+	}
+
+	summary = &types.PackageSummary{Name: pkgName}
+
+	for _, t := range tags {
+		if t.version.Prerelease() == "" {
+			if summary.LatestVersion == "" {
+				summary.LatestVersion = t.version.String()
+			}
+		} else if summary.LatestPrerelease == "" {
+			summary.LatestPrerelease = t.version.String()
+		}
+
+		when := t.tag.Tagger.When
+		if summary.FirstPublished == nil || when.Before(*summary.FirstPublished) {
+			summary.FirstPublished = &when
+		}
+		if summary.LastPublished == nil || when.After(*summary.LastPublished) {
+			summary.LastPublished = &when
+		}
+	}
+
+	summary.Size, err = repoSize(rPath)
+	if err != nil {
+		err = errors.Wrapf(err, "Failed to calculate repo size [%s]", pkgName)
+		return
+	}
+
+	summary.Exported, err = isVisibleRepo(rPath)
+	if err != nil {
+		err = errors.Wrapf(err, "Failed to check whether the package is visible or not [%s]", pkgName)
+		return
+	}
+
+	return
+}
+
 // DeletePackage removes package data from vcs storage.
 func DeletePackage(pkgName string) (err error) {
-	repoPath, err := packageRepoPath(pkgName)
+	repoPath, err := lookupPackageRepoPath(pkgName)
 	if err != nil {
 		return
 	}
@@ -272,3 +576,115 @@ func DeletePackage(pkgName string) (err error) {
 
 	return
 }
+
+// DeletedPackageSnapshots lists the soft deleted repos of a package from
+// both the public and the private repo root, sorted from the latest to the
+// oldest deletion.
+func DeletedPackageSnapshots(pkgName string) (snapshots []*types.PackageSnapshot, err error) {
+	snapshots = []*types.PackageSnapshot{}
+	prefix := fmt.Sprintf("%s-", repoName(pkgName))
+
+	for _, root := range packageRepoRoots() {
+		files, err := ioutil.ReadDir(root)
+		if err != nil {
+			if os.IsNotExist(err) {
+				continue
+			}
+			err = errors.Wrap(err, "Unable to read the repo root dir")
+			return nil, err
+		}
+
+		for _, f := range files {
+			name := f.Name()
+			if !f.IsDir() || !strings.HasPrefix(name, prefix) || !isDeletedRepoName(name) {
+				continue
+			}
+
+			id := strings.TrimSuffix(strings.TrimPrefix(name, prefix), ".deleted")
+			deletedAt, err := helper.ParseTime(id)
+			if err != nil {
+				continue
+			}
+
+			size, err := repoSize(filepath.Join(root, name))
+			if err != nil {
+				err = errors.Wrapf(err, "Failed to calculate repo size of snapshot %s [%s]", id, pkgName)
+				return nil, err
+			}
+
+			snapshots = append(snapshots, &types.PackageSnapshot{
+				ID:        id,
+				DeletedAt: deletedAt,
+				Size:      size,
+				Private:   isPrivateRepoRoot(root),
+			})
+		}
+	}
+
+	sort.Slice(snapshots, func(i, j int) bool {
+		return snapshots[i].DeletedAt.After(snapshots[j].DeletedAt)
+	})
+
+	return
+}
+
+// RestorePackage moves a soft deleted repo of a package back into the repo
+// root it was deleted from. Only the public repos are exported again. It
+// refuses if a live public or private repo of the package exists.
+func RestorePackage(pkgName, snapshotID string) (err error) {
+	_, err = helper.ParseTime(snapshotID)
+	if err != nil {
+		err = constants.ErrSnapshotNotFound
+		return
+	}
+
+	repoDelPath, private, err := findRepoDir(fmt.Sprintf("%s-%s.deleted", repoName(pkgName), snapshotID))
+	if err != nil {
+		err = errors.Wrapf(err, "Failed to check snapshot path existence [%s]", pkgName)
+		return
+	}
+
+	if repoDelPath == "" {
+		err = constants.ErrSnapshotNotFound
+		return
+	}
+
+	livePath, err := lookupPackageRepoPath(pkgName)
+	if err != nil {
+		return
+	}
+
+	exists, err := fs.Exists(livePath)
+	if err != nil {
+		err = errors.Wrapf(err, "Failed to check package path existence [%s]", pkgName)
+		return
+	}
+
+	if exists {
+		err = constants.ErrPackageExists
+		return
+	}
+
+	repoPath, err := filepath.Abs(filepath.Join(filepath.Dir(repoDelPath), repoName(pkgName)))
+	if err != nil {
+		return
+	}
+
+	err = os.Rename(repoDelPath, repoPath)
+	if err != nil {
+		err = errors.Wrapf(err, "Failed to rename deleted repo to package repo [%s]", pkgName)
+		return
+	}
+
+	if private {
+		return
+	}
+
+	err = makeVisibleRepo(repoPath)
+	if err != nil {
+		err = errors.Wrapf(err, "Failed to export restored repo [%s]", pkgName)
+		return
+	}
+
+	return
+}
@@ -0,0 +1,248 @@
+package vcs
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/Masterminds/semver"
+	"github.com/pkg/errors"
+	git "gopkg.in/src-d/go-git.v4"
+	"gopx.io/gopx-common/fs"
+	"gopx.io/gopx-vcs-api/api/v1/constants"
+	"gopx.io/gopx-vcs-api/api/v1/types"
+	"gopx.io/gopx-vcs-api/pkg/config"
+)
+
+// PackagePublishPolicy returns the publish policy which applies to the
+// package along with the overrides of the package.
+func PackagePublishPolicy(pkgName string) (policy *types.PackagePublishPolicy, err error) {
+	rPath, err := packageRepoDir(pkgName)
+	if err != nil {
+		return
+	}
+
+	overrides, err := readPublishPolicyOverrides(rPath)
+	if err != nil {
+		return
+	}
+
+	policy = &types.PackagePublishPolicy{
+		Policy:    applyPublishPolicyOverrides(overrides),
+		Overrides: *overrides,
+	}
+
+	return
+}
+
+// UpdatePackagePublishPolicy replaces the publish policy overrides of the
+// package and returns the resulting policy.
+func UpdatePackagePublishPolicy(pkgName string, overrides *types.PublishPolicyOverrides) (policy *types.PackagePublishPolicy, err error) {
+	rPath, err := packageRepoDir(pkgName)
+	if err != nil {
+		return
+	}
+
+	err = writePublishPolicyOverrides(rPath, overrides)
+	if err != nil {
+		return
+	}
+
+	policy = &types.PackagePublishPolicy{
+		Policy:    applyPublishPolicyOverrides(overrides),
+		Overrides: *overrides,
+	}
+
+	return
+}
+
+// packageRepoDir returns the repo path of an existing public or private
+// package.
+func packageRepoDir(pkgName string) (rPath string, err error) {
+	rPath, err = lookupPackageRepoPath(pkgName)
+	if err != nil {
+		return
+	}
+
+	exists, err := fs.Exists(rPath)
+	if err != nil {
+		err = errors.Wrapf(err, "Failed to check package path existence [%s]", pkgName)
+		return
+	}
+
+	if !exists {
+		err = constants.ErrPackageNotFound
+		return
+	}
+
+	return
+}
+
+// checkPublishPolicy checks the version to publish against the publish
+// policy of the package repo at rPath and its published versions. It also
+// reports whether the version becomes the latest one, i.e. it's greater than
+// all the published versions. The violations are reported with
+// ErrPolicyViolation.
+func checkPublishPolicy(rPath, pkgVersion string) (latest bool, err error) {
+	v, err := semver.NewVersion(pkgVersion)
+	if err != nil {
+		return
+	}
+
+	repo, err := git.PlainOpen(rPath)
+	if err != nil {
+		err = errors.Wrapf(err, "Package repo couldn't be opened [%s]", extractPkgName(rPath))
+		return
+	}
+
+	tags, err := packageRepoTags(repo)
+	if err != nil {
+		err = errors.Wrapf(err, "Couldn't read release tags [%s]", extractPkgName(rPath))
+		return
+	}
+
+	overrides, err := readPublishPolicyOverrides(rPath)
+	if err != nil {
+		return
+	}
+	policy := applyPublishPolicyOverrides(overrides)
+
+	// The tags are sorted from the newest to the oldest one.
+	if len(tags) == 0 {
+		return true, nil
+	}
+	latestVer := tags[0].version
+
+	if policy.DenyBackports && v.LessThan(latestVer) {
+		err = errors.Wrapf(constants.ErrPolicyViolation, "Version %s is lower than the latest version %s, backports are not allowed", v, latestVer)
+		return
+	}
+
+	if policy.DenyPrereleaseAfterStable && v.Prerelease() != "" {
+		for _, t := range tags {
+			if t.version.Prerelease() == "" && sameVersionCore(t.version, v) {
+				err = errors.Wrapf(constants.ErrPolicyViolation, "Version %s is a pre-release of the already published version %s", v, t.version)
+				return
+			}
+		}
+	}
+
+	if policy.DenyV0Skips && v.Major() == 0 {
+		err = checkV0Skip(tags, v)
+		if err != nil {
+			return
+		}
+	}
+
+	if policy.MaxMajorJump > 0 && v.Major()-latestVer.Major() > int64(policy.MaxMajorJump) {
+		err = errors.Wrapf(constants.ErrPolicyViolation, "Version %s increases the major version by more than %d over the latest version %s", v, policy.MaxMajorJump, latestVer)
+		return
+	}
+
+	return v.GreaterThan(latestVer), nil
+}
+
+// checkV0Skip checks that a new v0 version follows the highest published v0
+// version with the next patch or the next minor version. The pre-releases
+// are checked by their MAJOR.MINOR.PATCH part.
+func checkV0Skip(tags []*packageTag, v *semver.Version) error {
+	var highest *semver.Version
+	for _, t := range tags {
+		if t.version.Major() == 0 {
+			highest = t.version
+			break
+		}
+	}
+
+	if highest == nil || !versionCoreGreater(v, highest) {
+		return nil
+	}
+
+	nextPatch := v.Minor() == highest.Minor() && v.Patch() == highest.Patch()+1
+	nextMinor := v.Minor() == highest.Minor()+1 && v.Patch() == 0
+	if nextPatch || nextMinor {
+		return nil
+	}
+
+	return errors.Wrapf(
+		constants.ErrPolicyViolation,
+		"Version %s skips versions after %s, expected 0.%d.%d or 0.%d.0",
+		v, versionCore(highest), highest.Minor(), highest.Patch()+1, highest.Minor()+1,
+	)
+}
+
+func sameVersionCore(a, b *semver.Version) bool {
+	return a.Major() == b.Major() && a.Minor() == b.Minor() && a.Patch() == b.Patch()
+}
+
+func versionCoreGreater(a, b *semver.Version) bool {
+	if a.Major() != b.Major() {
+		return a.Major() > b.Major()
+	}
+	if a.Minor() != b.Minor() {
+		return a.Minor() > b.Minor()
+	}
+	return a.Patch() > b.Patch()
+}
+
+func versionCore(v *semver.Version) string {
+	return fmt.Sprintf("%d.%d.%d", v.Major(), v.Minor(), v.Patch())
+}
+
+// applyPublishPolicyOverrides returns the registry publish policy with the
+// package overrides applied.
+func applyPublishPolicyOverrides(overrides *types.PublishPolicyOverrides) types.PublishPolicy {
+	policy := types.PublishPolicy(config.VCS.PublishPolicy)
+
+	if overrides.DenyBackports != nil {
+		policy.DenyBackports = *overrides.DenyBackports
+	}
+	if overrides.DenyPrereleaseAfterStable != nil {
+		policy.DenyPrereleaseAfterStable = *overrides.DenyPrereleaseAfterStable
+	}
+	if overrides.DenyV0Skips != nil {
+		policy.DenyV0Skips = *overrides.DenyV0Skips
+	}
+	if overrides.MaxMajorJump != nil {
+		policy.MaxMajorJump = *overrides.MaxMajorJump
+	}
+
+	return policy
+}
+
+func readPublishPolicyOverrides(rPath string) (overrides *types.PublishPolicyOverrides, err error) {
+	overrides = &types.PublishPolicyOverrides{}
+
+	data, err := ioutil.ReadFile(filepath.Join(rPath, constants.RepoPublishPolicyFileName))
+	if err != nil {
+		if os.IsNotExist(err) {
+			err = nil
+		}
+		return
+	}
+
+	err = json.Unmarshal(data, overrides)
+	if err != nil {
+		err = errors.Wrapf(err, "Invalid %s file [%s]", constants.RepoPublishPolicyFileName, extractPkgName(rPath))
+		return
+	}
+
+	return
+}
+
+func writePublishPolicyOverrides(rPath string, overrides *types.PublishPolicyOverrides) (err error) {
+	data, err := json.MarshalIndent(overrides, "", "  ")
+	if err != nil {
+		return
+	}
+
+	err = ioutil.WriteFile(filepath.Join(rPath, constants.RepoPublishPolicyFileName), data, 0644)
+	if err != nil {
+		err = errors.Wrapf(err, "Unable to write %s file [%s]", constants.RepoPublishPolicyFileName, extractPkgName(rPath))
+		return
+	}
+
+	return
+}
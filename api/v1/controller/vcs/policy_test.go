@@ -0,0 +1,127 @@
+package vcs
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/pkg/errors"
+	git "gopkg.in/src-d/go-git.v4"
+	"gopx.io/gopx-vcs-api/api/v1/constants"
+	"gopx.io/gopx-vcs-api/api/v1/types"
+	"gopx.io/gopx-vcs-api/pkg/config"
+)
+
+// newTaggedRepo creates a repo with a release tag for each of the versions.
+func newTaggedRepo(t *testing.T, versions ...string) string {
+	rPath, err := ioutil.TempDir("", "gopx-policy-test-")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	repo, err := git.PlainInit(rPath, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	wt, err := repo.Worktree()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, v := range versions {
+		err = ioutil.WriteFile(filepath.Join(rPath, "version"), []byte(v), 0644)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		_, err = wt.Add("version")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		sig := vcsRepoTaggerSignature()
+		hash, err := wt.Commit(v, &git.CommitOptions{Author: sig})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		_, err = repo.CreateTag("v"+v, hash, &git.CreateTagOptions{Tagger: sig, Message: "Released v" + v})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	return rPath
+}
+
+func TestCheckPublishPolicy(t *testing.T) {
+	saved := config.VCS.PublishPolicy
+	defer func() { config.VCS.PublishPolicy = saved }()
+
+	rPath := newTaggedRepo(t, "0.1.0", "1.0.0", "1.2.0")
+	defer os.RemoveAll(rPath)
+
+	no := false
+	cases := []struct {
+		name      string
+		policy    types.PublishPolicy
+		overrides *types.PublishPolicyOverrides
+		version   string
+		latest    bool
+		err       error
+	}{
+		{name: "zero policy backport", version: "1.1.5"},
+		{name: "zero policy major jump", version: "5.0.0", latest: true},
+		{name: "zero policy v0 skip", version: "0.3.0"},
+		{name: "denied backport", policy: types.PublishPolicy{DenyBackports: true}, version: "1.1.5", err: constants.ErrPolicyViolation},
+		{name: "denied backport, newer version", policy: types.PublishPolicy{DenyBackports: true}, version: "1.3.0", latest: true},
+		{name: "backport allowed by the package", policy: types.PublishPolicy{DenyBackports: true}, overrides: &types.PublishPolicyOverrides{DenyBackports: &no}, version: "1.1.5"},
+		{name: "denied pre-release of a stable version", policy: types.PublishPolicy{DenyPrereleaseAfterStable: true}, version: "1.2.0-rc.1", err: constants.ErrPolicyViolation},
+		{name: "denied pre-release, new version", policy: types.PublishPolicy{DenyPrereleaseAfterStable: true}, version: "1.3.0-rc.1", latest: true},
+		{name: "denied v0 skip", policy: types.PublishPolicy{DenyV0Skips: true}, version: "0.3.0", err: constants.ErrPolicyViolation},
+		{name: "denied v0 skip, next patch", policy: types.PublishPolicy{DenyV0Skips: true}, version: "0.1.1"},
+		{name: "denied v0 skip, next minor", policy: types.PublishPolicy{DenyV0Skips: true}, version: "0.2.0"},
+		{name: "major jump over the limit", policy: types.PublishPolicy{MaxMajorJump: 1}, version: "3.0.0", err: constants.ErrPolicyViolation},
+		{name: "major jump within the limit", policy: types.PublishPolicy{MaxMajorJump: 1}, version: "2.0.0", latest: true},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			config.VCS.PublishPolicy = config.PublishPolicy(c.policy)
+
+			overrides := c.overrides
+			if overrides == nil {
+				overrides = &types.PublishPolicyOverrides{}
+			}
+			err := writePublishPolicyOverrides(rPath, overrides)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			latest, err := checkPublishPolicy(rPath, c.version)
+			if errors.Cause(err) != c.err {
+				t.Fatalf("got error %v, want %v", err, c.err)
+			}
+			if err == nil && latest != c.latest {
+				t.Errorf("got latest %t, want %t", latest, c.latest)
+			}
+		})
+	}
+}
+
+func TestCheckPublishPolicyFirstVersion(t *testing.T) {
+	saved := config.VCS.PublishPolicy
+	defer func() { config.VCS.PublishPolicy = saved }()
+
+	config.VCS.PublishPolicy = config.PublishPolicy{DenyBackports: true, DenyV0Skips: true, MaxMajorJump: 1}
+
+	rPath := newTaggedRepo(t)
+	defer os.RemoveAll(rPath)
+
+	latest, err := checkPublishPolicy(rPath, "0.5.0")
+	if err != nil || !latest {
+		t.Fatalf("got latest %t and error %v, want the first version to pass as latest", latest, err)
+	}
+}
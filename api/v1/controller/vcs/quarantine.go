@@ -0,0 +1,510 @@
+package vcs
+
+import (
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/Masterminds/semver"
+	"github.com/pkg/errors"
+	"gopkg.in/src-d/go-billy.v4/osfs"
+	"gopkg.in/src-d/go-git.v4/plumbing"
+	"gopkg.in/src-d/go-git.v4/plumbing/cache"
+	"gopkg.in/src-d/go-git.v4/plumbing/filemode"
+	"gopkg.in/src-d/go-git.v4/plumbing/object"
+	"gopkg.in/src-d/go-git.v4/storage/filesystem"
+	"gopx.io/gopx-common/fs"
+	"gopx.io/gopx-common/log"
+	"gopx.io/gopx-vcs-api/api/v1/constants"
+	"gopx.io/gopx-vcs-api/api/v1/controller/helper"
+	"gopx.io/gopx-vcs-api/api/v1/types"
+)
+
+// QuarantinedRepos lists the reports of the quarantined repos of both the
+// public and the private repo root sorted from the latest to the oldest
+// quarantine. The listed reports don't include the missing refs and
+// objects. The entries which can't be read are logged and skipped.
+func QuarantinedRepos() (reports []*types.QuarantineReport, err error) {
+	reports = []*types.QuarantineReport{}
+
+	for _, root := range packageRepoRoots() {
+		files, err := ioutil.ReadDir(root)
+		if err != nil {
+			if os.IsNotExist(err) {
+				continue
+			}
+			err = errors.Wrap(err, "Unable to read the repo root dir")
+			return nil, err
+		}
+
+		for _, f := range files {
+			if !f.IsDir() || !isCorruptedRepoName(f.Name()) {
+				continue
+			}
+
+			// A single unreadable entry mustn't hide the other quarantined repos.
+			report, err := readQuarantineReport(f.Name(), false)
+			if err != nil {
+				log.Error("Skipping quarantined repo %s: %s", f.Name(), err)
+				continue
+			}
+
+			report.MissingRefs = nil
+			report.BrokenRefs = nil
+			report.MissingObjects = nil
+			reports = append(reports, report)
+		}
+	}
+
+	sort.Slice(reports, func(i, j int) bool {
+		return reports[i].QuarantinedAt.After(reports[j].QuarantinedAt)
+	})
+
+	return
+}
+
+// QuarantinedRepo returns the diagnostic report of a quarantined repo. The
+// repo is inspected on demand if it was quarantined without a report.
+func QuarantinedRepo(id string) (report *types.QuarantineReport, err error) {
+	return readQuarantineReport(id, true)
+}
+
+// SalvageQuarantinedRepo rebuilds the refs of a quarantined repo from the
+// release tags which are still intact, then moves the repo back into the
+// repo root it was quarantined from. Only the public repos are exported
+// again. It refuses if a live public or private repo of the package exists.
+func SalvageQuarantinedRepo(id string) (result *types.SalvageResult, err error) {
+	qPath, pkgName, private, _, err := parseQuarantineID(id)
+	if err != nil {
+		return
+	}
+
+	livePath, err := lookupPackageRepoPath(pkgName)
+	if err != nil {
+		return
+	}
+
+	exists, err := fs.Exists(livePath)
+	if err != nil {
+		err = errors.Wrapf(err, "Failed to check package path existence [%s]", pkgName)
+		return
+	}
+
+	if exists {
+		err = constants.ErrPackageExists
+		return
+	}
+
+	tombstones, err := readVersionTombstones(qPath)
+	if err != nil {
+		return
+	}
+
+	ri := newRepoInspector(qPath)
+
+	tagIter, err := ri.s.IterEncodedObjects(plumbing.TagObject)
+	if err != nil {
+		err = errors.Wrapf(err, "Couldn't access tag objects [%s]", pkgName)
+		return
+	}
+
+	tags := map[string]*object.Tag{}
+	err = tagIter.ForEach(func(obj plumbing.EncodedObject) error {
+		tag, err := object.DecodeTag(ri.s, obj)
+		if err != nil {
+			return nil
+		}
+
+		tagVer, err := semver.NewVersion(tag.Name)
+		if err != nil {
+			return nil
+		}
+
+		for _, t := range tombstones {
+			tv, err := semver.NewVersion(t.Version)
+			if err == nil && tv.Equal(tagVer) {
+				return nil
+			}
+		}
+
+		if prev, ok := tags[tag.Name]; ok && !tagPublishedAfter(tag, prev) {
+			return nil
+		}
+
+		if ri.intact(tag.Hash) {
+			tags[tag.Name] = tag
+		}
+
+		return nil
+	})
+	if err != nil {
+		err = errors.Wrapf(err, "Couldn't iterate over tag objects [%s]", pkgName)
+		return
+	}
+
+	if len(tags) == 0 {
+		err = constants.ErrNothingToSalvage
+		return
+	}
+
+	err = clearRepoRefs(ri.s)
+	if err != nil {
+		err = errors.Wrapf(err, "Couldn't clear broken references [%s]", pkgName)
+		return
+	}
+
+	result = &types.SalvageResult{Package: pkgName, Versions: []string{}}
+
+	var latest *object.Tag
+	for name, tag := range tags {
+		err = ri.s.SetReference(plumbing.NewHashReference(plumbing.NewTagReferenceName(name), tag.Hash))
+		if err != nil {
+			err = errors.Wrapf(err, "Couldn't set reference for tag %s [%s]", name, pkgName)
+			return nil, err
+		}
+
+		if latest == nil || tagPublishedAfter(tag, latest) {
+			latest = tag
+		}
+		result.Versions = append(result.Versions, name)
+	}
+
+	sort.Strings(result.Versions)
+	result.Master = latest.Name
+
+	err = ri.s.SetReference(plumbing.NewHashReference(plumbing.Master, latest.Target))
+	if err != nil {
+		err = errors.Wrapf(err, "Couldn't set master reference [%s]", pkgName)
+		return nil, err
+	}
+
+	err = ri.s.SetReference(plumbing.NewSymbolicReference(plumbing.HEAD, plumbing.Master))
+	if err != nil {
+		err = errors.Wrapf(err, "Couldn't set HEAD reference [%s]", pkgName)
+		return nil, err
+	}
+
+	err = os.RemoveAll(filepath.Join(qPath, constants.RepoQuarantineReportFileName))
+	if err != nil {
+		err = errors.Wrapf(err, "Unable to remove quarantine report [%s]", pkgName)
+		return nil, err
+	}
+
+	repoPath, err := filepath.Abs(filepath.Join(filepath.Dir(qPath), repoName(pkgName)))
+	if err != nil {
+		return nil, err
+	}
+
+	err = os.Rename(qPath, repoPath)
+	if err != nil {
+		err = errors.Wrapf(err, "Failed to rename salvaged repo to package repo [%s]", pkgName)
+		return nil, err
+	}
+
+	if private {
+		return
+	}
+
+	err = makeVisibleRepo(repoPath)
+	if err != nil {
+		err = errors.Wrapf(err, "Failed to export salvaged repo [%s]", pkgName)
+		return nil, err
+	}
+
+	return
+}
+
+func writeQuarantineReport(qPath string, cause error) (err error) {
+	id := filepath.Base(qPath)
+	_, pkgName, private, quarantinedAt, err := parseQuarantineID(id)
+	if err != nil {
+		return
+	}
+
+	report := &types.QuarantineReport{
+		ID:            id,
+		Package:       pkgName,
+		QuarantinedAt: quarantinedAt,
+		Private:       private,
+	}
+
+	if cause != nil {
+		report.Error = cause.Error()
+	}
+
+	inspectRepoInto(qPath, report)
+
+	data, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return
+	}
+
+	err = ioutil.WriteFile(filepath.Join(qPath, constants.RepoQuarantineReportFileName), data, 0644)
+	if err != nil {
+		err = errors.Wrapf(err, "Unable to write %s file [%s]", constants.RepoQuarantineReportFileName, pkgName)
+		return
+	}
+
+	return
+}
+
+func readQuarantineReport(id string, inspect bool) (report *types.QuarantineReport, err error) {
+	qPath, pkgName, private, quarantinedAt, err := parseQuarantineID(id)
+	if err != nil {
+		return
+	}
+
+	report = &types.QuarantineReport{
+		ID:            id,
+		Package:       pkgName,
+		QuarantinedAt: quarantinedAt,
+		Private:       private,
+	}
+
+	data, err := ioutil.ReadFile(filepath.Join(qPath, constants.RepoQuarantineReportFileName))
+	if err != nil {
+		if !os.IsNotExist(err) {
+			err = errors.Wrapf(err, "Unable to read quarantine report %s", id)
+			return nil, err
+		}
+
+		err = nil
+		if inspect {
+			inspectRepoInto(qPath, report)
+		}
+		return
+	}
+
+	err = json.Unmarshal(data, report)
+	if err != nil {
+		err = errors.Wrapf(err, "Invalid quarantine report %s", id)
+		return nil, err
+	}
+	report.Private = private
+
+	return
+}
+
+// parseQuarantineID resolves the quarantined repo path, package name,
+// repo root kind and quarantine time from the quarantined repo dir name.
+// The public repo root is looked up before the private one.
+func parseQuarantineID(id string) (qPath, pkgName string, private bool, quarantinedAt time.Time, err error) {
+	if id != filepath.Base(id) || !isCorruptedRepoName(id) {
+		err = constants.ErrQuarantineNotFound
+		return
+	}
+
+	name := strings.TrimSuffix(id, ".corrupted")
+	idx := strings.LastIndex(name, "-")
+	if idx < 0 {
+		err = constants.ErrQuarantineNotFound
+		return
+	}
+
+	quarantinedAt, err = helper.ParseTime(name[idx+1:])
+	if err != nil {
+		err = constants.ErrQuarantineNotFound
+		return
+	}
+
+	pkgName = extractPkgName(name[:idx])
+
+	qPath, private, err = findRepoDir(id)
+	if err != nil {
+		err = errors.Wrapf(err, "Failed to check quarantined repo existence %s", id)
+		return
+	}
+
+	if qPath == "" {
+		err = constants.ErrQuarantineNotFound
+		return
+	}
+
+	return
+}
+
+// inspectRepoInto inspects the repo into the report. A failed inspection,
+// which is common for a corrupted repo, is recorded in the report instead.
+func inspectRepoInto(rPath string, report *types.QuarantineReport) {
+	err := inspectRepo(rPath, report)
+	if err != nil {
+		report.InspectError = err.Error()
+	}
+}
+
+// inspectRepo fills the missing and broken refs and the missing objects
+// of the repo into the report.
+func inspectRepo(rPath string, report *types.QuarantineReport) (err error) {
+	ri := newRepoInspector(rPath)
+
+	report.MissingRefs = []string{}
+	report.BrokenRefs = []string{}
+	report.MissingObjects = []string{}
+
+	head, err := ri.s.Reference(plumbing.HEAD)
+	switch {
+	case err == plumbing.ErrReferenceNotFound:
+		report.MissingRefs = append(report.MissingRefs, plumbing.HEAD.String())
+	case err != nil:
+		return errors.Wrap(err, "Couldn't access HEAD reference")
+	case head.Type() == plumbing.SymbolicReference:
+		_, err = ri.s.Reference(head.Target())
+		if err == plumbing.ErrReferenceNotFound {
+			report.MissingRefs = append(report.MissingRefs, head.Target().String())
+		} else if err != nil {
+			return errors.Wrapf(err, "Couldn't access %s reference", head.Target())
+		}
+	}
+
+	refIter, err := ri.s.IterReferences()
+	if err != nil {
+		return errors.Wrap(err, "Couldn't access references")
+	}
+	defer refIter.Close()
+
+	for {
+		ref, err := refIter.Next()
+		if err != nil {
+			if err == io.EOF {
+				break
+			}
+			return errors.Wrap(err, "Couldn't iterate over references")
+		}
+
+		if ref.Type() != plumbing.HashReference {
+			continue
+		}
+
+		if !ri.intact(ref.Hash()) {
+			report.BrokenRefs = append(report.BrokenRefs, ref.Name().String())
+		}
+	}
+
+	for h := range ri.missing {
+		report.MissingObjects = append(report.MissingObjects, h.String())
+	}
+	sort.Strings(report.MissingObjects)
+
+	return nil
+}
+
+func clearRepoRefs(s *filesystem.Storage) (err error) {
+	refIter, err := s.IterReferences()
+	if err != nil {
+		return
+	}
+
+	names := []plumbing.ReferenceName{}
+	err = refIter.ForEach(func(ref *plumbing.Reference) error {
+		if ref.Name() != plumbing.HEAD {
+			names = append(names, ref.Name())
+		}
+		return nil
+	})
+	if err != nil {
+		return
+	}
+
+	for _, name := range names {
+		err = s.RemoveReference(name)
+		if err != nil {
+			return
+		}
+	}
+
+	return
+}
+
+// repoInspector walks the object graph of a repo which can't be opened
+// as a regular git repository and tracks the missing objects.
+type repoInspector struct {
+	s       *filesystem.Storage
+	intacts map[plumbing.Hash]bool
+	missing map[plumbing.Hash]bool
+}
+
+func newRepoInspector(rPath string) *repoInspector {
+	return &repoInspector{
+		s:       filesystem.NewStorage(osfs.New(rPath), cache.NewObjectLRUDefault()),
+		intacts: map[plumbing.Hash]bool{},
+		missing: map[plumbing.Hash]bool{},
+	}
+}
+
+// intact reports whether the object and every object reachable from it
+// exist and can be decoded.
+func (ri *repoInspector) intact(h plumbing.Hash) bool {
+	visited := map[plumbing.Hash]bool{}
+	stack := []plumbing.Hash{h}
+	ok := true
+
+	for len(stack) > 0 {
+		cur := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+
+		if visited[cur] || ri.intacts[cur] {
+			continue
+		}
+		visited[cur] = true
+
+		next, found := ri.references(cur)
+		if !found {
+			ri.missing[cur] = true
+			ok = false
+			continue
+		}
+		stack = append(stack, next...)
+	}
+
+	if ok {
+		for v := range visited {
+			ri.intacts[v] = true
+		}
+	}
+
+	return ok
+}
+
+// references returns the hashes of the objects referenced by the object.
+func (ri *repoInspector) references(h plumbing.Hash) (hashes []plumbing.Hash, found bool) {
+	obj, err := ri.s.EncodedObject(plumbing.AnyObject, h)
+	if err != nil {
+		return nil, false
+	}
+
+	switch obj.Type() {
+	case plumbing.CommitObject:
+		commit, err := object.DecodeCommit(ri.s, obj)
+		if err != nil {
+			return nil, false
+		}
+		hashes = append(hashes, commit.TreeHash)
+		hashes = append(hashes, commit.ParentHashes...)
+	case plumbing.TreeObject:
+		tree, err := object.DecodeTree(ri.s, obj)
+		if err != nil {
+			return nil, false
+		}
+		for _, e := range tree.Entries {
+			if e.Mode != filemode.Submodule {
+				hashes = append(hashes, e.Hash)
+			}
+		}
+	case plumbing.TagObject:
+		tag, err := object.DecodeTag(ri.s, obj)
+		if err != nil {
+			return nil, false
+		}
+		hashes = append(hashes, tag.Target)
+	case plumbing.BlobObject:
+	default:
+		return nil, false
+	}
+
+	return hashes, true
+}
@@ -0,0 +1,208 @@
+package vcs
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/pkg/errors"
+	"gopx.io/gopx-common/fs"
+	"gopx.io/gopx-vcs-api/api/v1/constants"
+	"gopx.io/gopx-vcs-api/api/v1/types"
+)
+
+// packageReadKey represents a stored read credential of a private package.
+// Only the SHA-256 hash of the key is stored.
+type packageReadKey struct {
+	ID        string    `json:"id"`
+	Hash      string    `json:"hash"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// PackageReadKeys lists the read credentials of a private package
+// without the key values.
+func PackageReadKeys(pkgName string) (keys []*types.PackageReadKey, err error) {
+	rPath, err := privatePackageRepoDir(pkgName)
+	if err != nil {
+		return
+	}
+
+	stored, err := readPackageReadKeys(rPath)
+	if err != nil {
+		return
+	}
+
+	keys = make([]*types.PackageReadKey, 0, len(stored))
+	for _, k := range stored {
+		keys = append(keys, &types.PackageReadKey{
+			ID:        k.ID,
+			CreatedAt: k.CreatedAt,
+		})
+	}
+
+	return
+}
+
+// CreatePackageReadKey generates a new read credential for a private package.
+// The key value is only returned here and can't be retrieved later.
+func CreatePackageReadKey(pkgName string) (key *types.PackageReadKey, err error) {
+	rPath, err := privatePackageRepoDir(pkgName)
+	if err != nil {
+		return
+	}
+
+	stored, err := readPackageReadKeys(rPath)
+	if err != nil {
+		return
+	}
+
+	id, err := randomHex(8)
+	if err != nil {
+		return
+	}
+
+	value, err := randomHex(32)
+	if err != nil {
+		return
+	}
+
+	k := &packageReadKey{
+		ID:        id,
+		Hash:      hashReadKey(value),
+		CreatedAt: time.Now(),
+	}
+
+	err = writePackageReadKeys(rPath, append(stored, k))
+	if err != nil {
+		return
+	}
+
+	key = &types.PackageReadKey{
+		ID:        k.ID,
+		Key:       value,
+		CreatedAt: k.CreatedAt,
+	}
+
+	return
+}
+
+// DeletePackageReadKey revokes a read credential of a private package.
+func DeletePackageReadKey(pkgName, keyID string) (err error) {
+	rPath, err := privatePackageRepoDir(pkgName)
+	if err != nil {
+		return
+	}
+
+	stored, err := readPackageReadKeys(rPath)
+	if err != nil {
+		return
+	}
+
+	for i, k := range stored {
+		if k.ID == keyID {
+			return writePackageReadKeys(rPath, append(stored[:i], stored[i+1:]...))
+		}
+	}
+
+	return constants.ErrReadKeyNotFound
+}
+
+// CheckPackageReadKey checks whether the key is a valid read credential
+// of the private package.
+func CheckPackageReadKey(pkgName, key string) (ok bool, err error) {
+	rPath, err := privatePackageRepoDir(pkgName)
+	if err != nil {
+		if err == constants.ErrPackageNotFound {
+			err = nil
+		}
+		return
+	}
+
+	stored, err := readPackageReadKeys(rPath)
+	if err != nil {
+		return
+	}
+
+	hash := []byte(hashReadKey(key))
+	for _, k := range stored {
+		if subtle.ConstantTimeCompare(hash, []byte(k.Hash)) == 1 {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+// privatePackageRepoDir returns the repo path of an existing private package.
+func privatePackageRepoDir(pkgName string) (rPath string, err error) {
+	rPath, err = privatePackageRepoPath(pkgName)
+	if err != nil {
+		return
+	}
+
+	exists, err := fs.Exists(rPath)
+	if err != nil {
+		err = errors.Wrapf(err, "Failed to check private package path existence [%s]", pkgName)
+		return
+	}
+
+	if !exists {
+		err = constants.ErrPackageNotFound
+		return
+	}
+
+	return
+}
+
+func readPackageReadKeys(rPath string) (keys []*packageReadKey, err error) {
+	data, err := ioutil.ReadFile(filepath.Join(rPath, constants.RepoReadKeysFileName))
+	if err != nil {
+		if os.IsNotExist(err) {
+			err = nil
+		}
+		return
+	}
+
+	err = json.Unmarshal(data, &keys)
+	if err != nil {
+		err = errors.Wrapf(err, "Invalid %s file [%s]", constants.RepoReadKeysFileName, extractPkgName(rPath))
+		return
+	}
+
+	return
+}
+
+func writePackageReadKeys(rPath string, keys []*packageReadKey) (err error) {
+	data, err := json.MarshalIndent(keys, "", "  ")
+	if err != nil {
+		return
+	}
+
+	err = ioutil.WriteFile(filepath.Join(rPath, constants.RepoReadKeysFileName), data, 0600)
+	if err != nil {
+		err = errors.Wrapf(err, "Unable to write %s file [%s]", constants.RepoReadKeysFileName, extractPkgName(rPath))
+		return
+	}
+
+	return
+}
+
+func hashReadKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+func randomHex(n int) (string, error) {
+	b := make([]byte, n)
+	_, err := rand.Read(b)
+	if err != nil {
+		return "", errors.Wrap(err, "Failed to generate random bytes")
+	}
+	return hex.EncodeToString(b), nil
+}
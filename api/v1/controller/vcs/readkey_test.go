@@ -0,0 +1,104 @@
+package vcs
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/pkg/errors"
+	"gopx.io/gopx-vcs-api/api/v1/constants"
+	"gopx.io/gopx-vcs-api/pkg/config"
+)
+
+func TestHashReadKey(t *testing.T) {
+	cases := []struct {
+		key  string
+		hash string
+	}{
+		{key: "", hash: "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"},
+		{key: "abc", hash: "ba7816bf8f01cfea414140de5dae2223b00361a396177a9cb410ff61f20015ad"},
+	}
+
+	for _, c := range cases {
+		if hash := hashReadKey(c.key); hash != c.hash {
+			t.Errorf("got hash %s of %q, want %s", hash, c.key, c.hash)
+		}
+	}
+}
+
+func TestPackageReadKeys(t *testing.T) {
+	saved := config.VCS.PrivateRepoRoot
+	defer func() { config.VCS.PrivateRepoRoot = saved }()
+
+	root, err := ioutil.TempDir("", "gopx-readkey-test-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(root)
+	config.VCS.PrivateRepoRoot = root
+
+	rPath, err := privatePackageRepoPath("foo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = os.MkdirAll(rPath, 0755)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	key, err := CreatePackageReadKey("foo")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := ioutil.ReadFile(filepath.Join(rPath, constants.RepoReadKeysFileName))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(data), key.Key) {
+		t.Fatal("the key value is stored in plain text")
+	}
+
+	cases := []struct {
+		name    string
+		pkgName string
+		key     string
+		ok      bool
+	}{
+		{name: "valid key", pkgName: "foo", key: key.Key, ok: true},
+		{name: "other key", pkgName: "foo", key: strings.Repeat("0", len(key.Key))},
+		{name: "key prefix", pkgName: "foo", key: key.Key[:len(key.Key)-1]},
+		{name: "key hash", pkgName: "foo", key: hashReadKey(key.Key)},
+		{name: "empty key", pkgName: "foo"},
+		{name: "unknown package", pkgName: "bar", key: key.Key},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			ok, err := CheckPackageReadKey(c.pkgName, c.key)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if ok != c.ok {
+				t.Errorf("got %t, want %t", ok, c.ok)
+			}
+		})
+	}
+
+	err = DeletePackageReadKey("foo", key.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ok, err := CheckPackageReadKey("foo", key.Key)
+	if err != nil || ok {
+		t.Fatalf("got %t and error %v for a deleted key", ok, err)
+	}
+
+	err = DeletePackageReadKey("foo", key.ID)
+	if errors.Cause(err) != constants.ErrReadKeyNotFound {
+		t.Fatalf("got error %v, want %v", err, constants.ErrReadKeyNotFound)
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"io/ioutil"
 	"os"
 	"path/filepath"
+	"strings"
 	"time"
 
 	"github.com/Masterminds/semver"
@@ -13,6 +14,7 @@ import (
 	"gopkg.in/src-d/go-git.v4/plumbing"
 	"gopkg.in/src-d/go-git.v4/plumbing/object"
 	"gopx.io/gopx-common/fs"
+	"gopx.io/gopx-common/log"
 	"gopx.io/gopx-vcs-api/api/v1/constants"
 	"gopx.io/gopx-vcs-api/api/v1/controller/helper"
 	"gopx.io/gopx-vcs-api/api/v1/types"
@@ -51,11 +53,91 @@ func vcsRepoCommitMessage(tagName string) string {
 	return fmt.Sprintf("Update package to version %s", tagName)
 }
 
-func vcsRepoTagMessage(tagName string) string {
-	return fmt.Sprintf("Released %s", tagName)
+// tagTrailer represents a "Key: value" trailer line of a release tag
+// message, which records the metadata of the release.
+type tagTrailer struct {
+	key   string
+	value string
 }
 
-func vcsRepoCreateTag(repo *git.Repository, tagName string, tagger *object.Signature, message string) (err error) {
+func vcsRepoTagMessage(tagName, notes string, trailers ...tagTrailer) string {
+	msg := fmt.Sprintf("Released %s", tagName)
+	if notes != "" {
+		msg += "\n\n" + notes
+	}
+	if len(trailers) == 0 {
+		return msg
+	}
+
+	msg += "\n"
+	for _, t := range trailers {
+		msg += fmt.Sprintf("\n%s: %s", t.key, t.value)
+	}
+
+	return msg + "\n"
+}
+
+// tagMessageTrailer returns the value of the trailer key in the last
+// paragraph of the release tag message, or an empty string if there is none.
+// The keys are matched case-insensitively.
+func tagMessageTrailer(message, key string) string {
+	_, trailers := splitTagMessage(message)
+
+	for _, t := range trailers {
+		if strings.EqualFold(t.key, key) {
+			return t.value
+		}
+	}
+
+	return ""
+}
+
+// tagMessageNotes returns the release notes of the release tag message, the
+// paragraphs between the title and the trailers.
+func tagMessageNotes(message string) string {
+	paras, _ := splitTagMessage(message)
+	if len(paras) < 2 {
+		return ""
+	}
+
+	return strings.Join(paras[1:], "\n\n")
+}
+
+// splitTagMessage splits the release tag message into its paragraphs, the
+// title first, and the trailers of its last paragraph. The last paragraph
+// is only taken as trailers if it follows the title and all of its lines
+// are trailers.
+func splitTagMessage(message string) (paras []string, trailers []tagTrailer) {
+	paras = strings.Split(strings.TrimSpace(tagMessageBody(message)), "\n\n")
+	if len(paras) < 2 {
+		return
+	}
+
+	trailers, ok := parseTagTrailers(paras[len(paras)-1])
+	if !ok {
+		return paras, nil
+	}
+
+	return paras[:len(paras)-1], trailers
+}
+
+// parseTagTrailers parses the "Key: value" lines of the paragraph. The keys
+// can't be empty or contain spaces. It reports false if any of the lines is
+// not a trailer.
+func parseTagTrailers(para string) (trailers []tagTrailer, ok bool) {
+	for _, line := range strings.Split(para, "\n") {
+		parts := strings.SplitN(line, ": ", 2)
+		if len(parts) != 2 || parts[0] == "" || strings.ContainsAny(parts[0], " \t") {
+			return nil, false
+		}
+
+		trailers = append(trailers, tagTrailer{key: parts[0], value: strings.TrimSpace(parts[1])})
+	}
+
+	return trailers, true
+}
+
+func vcsRepoCreateTag(repo *git.Repository, tagName string, tagger *object.Signature, message string) (tagObj *object.Tag, err error) {
 	wt, err := repo.Worktree()
 	if err != nil {
 		err = errors.Wrapf(err, "Failed to access the worktree")
@@ -84,6 +166,12 @@ func vcsRepoCreateTag(repo *git.Repository, tagName string, tagger *object.Signa
 		Target:     headRef.Hash(),
 	}
 
+	err = signTag(&tag)
+	if err != nil {
+		err = errors.Wrapf(err, "Failed to sign the tag")
+		return
+	}
+
 	enObj := repo.Storer.NewEncodedObject()
 	tag.Encode(enObj)
 
@@ -102,10 +190,24 @@ func vcsRepoCreateTag(repo *git.Repository, tagName string, tagger *object.Signa
 		return
 	}
 
+	tagObj, err = repo.TagObject(hash)
+	if err != nil {
+		err = errors.Wrapf(err, "Couldn't access the created tag object")
+		return
+	}
+
 	return
 }
 
+// tagNameFromVersion returns the release tag name of the version. The
+// versions which can't be published, e.g. the ones with build metadata, are
+// refused instead of being mapped onto the tag of another version.
 func tagNameFromVersion(pkgVersion string) (tagName string, err error) {
+	err = validatePackageVersion(pkgVersion)
+	if err != nil {
+		return
+	}
+
 	v, err := semver.NewVersion(pkgVersion)
 	if err != nil {
 		return
@@ -125,34 +227,141 @@ func tagNameFromVersion(pkgVersion string) (tagName string, err error) {
 	return
 }
 
+func packageRepoRoot() string {
+	return config.VCS.RepoRoot
+}
+
+// packageRepoRoots returns the public and the private repo roots.
+func packageRepoRoots() []string {
+	return []string{config.VCS.RepoRoot, config.VCS.PrivateRepoRoot}
+}
+
+func isPrivateRepoRoot(root string) bool {
+	return filepath.Clean(root) == filepath.Clean(config.VCS.PrivateRepoRoot)
+}
+
+// findRepoDir looks up the dir name under the public and then the private
+// repo root. The returned path is empty if none of the roots contains it.
+func findRepoDir(name string) (rPath string, private bool, err error) {
+	for _, root := range packageRepoRoots() {
+		p := filepath.Join(root, name)
+
+		exists, err := fs.Exists(p)
+		if err != nil {
+			return "", false, err
+		}
+
+		if exists {
+			return p, isPrivateRepoRoot(root), nil
+		}
+	}
+
+	return
+}
+
 func packageRepoPath(pkgName string) (rPath string, err error) {
 	rPath = filepath.Join(config.VCS.RepoRoot, repoName(pkgName))
 	rPath, err = filepath.Abs(rPath)
 	return
 }
 
-func resolvePackageRepo(pkgName string) (err error) {
-	rPath, err := packageRepoPath(pkgName)
+func privatePackageRepoPath(pkgName string) (rPath string, err error) {
+	rPath = filepath.Join(config.VCS.PrivateRepoRoot, repoName(pkgName))
+	rPath, err = filepath.Abs(rPath)
+	return
+}
+
+// lookupPackageRepoPath returns the repo path of the package from the public
+// repo root, or from the private repo root if only a private package exists.
+func lookupPackageRepoPath(pkgName string) (rPath string, err error) {
+	rPath, err = packageRepoPath(pkgName)
+	if err != nil {
+		return
+	}
+
+	exists, err := fs.Exists(rPath)
+	if err != nil || exists {
+		return
+	}
+
+	privPath, err := privatePackageRepoPath(pkgName)
+	if err != nil {
+		return
+	}
+
+	exists, err = fs.Exists(privPath)
 	if err != nil {
 		return
 	}
 
+	if exists {
+		rPath = privPath
+	}
+
+	return
+}
+
+// exportedPackageRepoPath returns the repo path of the public package if its
+// repo is exported, which is the only case it can be served to the clients.
+func exportedPackageRepoPath(pkgName string) (rPath string, err error) {
+	if pkgName == "" {
+		err = constants.ErrPackageNotFound
+		return
+	}
+
+	rPath, err = packageRepoPath(pkgName)
+	if err != nil {
+		return
+	}
+
+	exists, err := fs.Exists(rPath)
+	if err != nil {
+		return
+	}
+
+	if !exists {
+		err = constants.ErrPackageNotFound
+		return
+	}
+
+	visible, err := isVisibleRepo(rPath)
+	if err != nil {
+		return
+	}
+
+	if !visible {
+		err = constants.ErrPackageNotFound
+		return
+	}
+
+	return
+}
+
+// resolvePackageRepo makes sure a readable bare repo exists at rPath. The
+// existing repo is quarantined if it can't be opened, or if it should be
+// exported but isn't.
+func resolvePackageRepo(rPath string, exported bool) (err error) {
 	if exists, err := fs.Exists(rPath); err != nil {
 		return err
 	} else if exists {
-		visible, err := isVisibleRepo(rPath)
-		if err != nil {
-			return err
+		visible := true
+		if exported {
+			visible, err = isVisibleRepo(rPath)
+			if err != nil {
+				return err
+			}
 		}
 
+		cause := errors.New("Package repo is not exported")
 		if visible {
 			_, err = git.PlainOpen(rPath)
 			if err == nil || err != git.ErrRepositoryNotExists {
 				return err
 			}
+			cause = err
 		}
 
-		err = careCorruptedRepo(rPath)
+		err = careCorruptedRepo(rPath, cause)
 		if err != nil {
 			return err
 		}
@@ -160,14 +369,17 @@ func resolvePackageRepo(pkgName string) (err error) {
 
 	_, err = git.PlainInit(rPath, true)
 	if err != nil {
-		err = errors.Wrapf(err, "Failed to initialize the package repo [%s]", pkgName)
+		err = errors.Wrapf(err, "Failed to initialize the package repo [%s]", extractPkgName(rPath))
 		return
 	}
 
 	return
 }
 
-func careCorruptedRepo(rPath string) (err error) {
+// careCorruptedRepo moves the unreadable repo into quarantine and records
+// a diagnostic report of it. The report is best-effort, the quarantined repo
+// is hidden even if the report can't be recorded.
+func careCorruptedRepo(rPath string, cause error) (err error) {
 	repoCorrPath := fmt.Sprintf("%s-%s.corrupted", rPath, helper.FormatTime(time.Now()))
 
 	err = os.Rename(rPath, repoCorrPath)
@@ -176,6 +388,11 @@ func careCorruptedRepo(rPath string) (err error) {
 		return
 	}
 
+	reportErr := writeQuarantineReport(repoCorrPath, cause)
+	if reportErr != nil {
+		log.Error("Failed to record quarantine report [%s]: %s", extractPkgName(rPath), reportErr)
+	}
+
 	err = hideRepo(repoCorrPath)
 	if err != nil {
 		err = errors.Wrapf(err, "Failed to hide corrupted repo [%s]", extractPkgName(rPath))
@@ -215,27 +432,50 @@ func isVisibleRepo(rPath string) (ok bool, err error) {
 	return
 }
 
+func repoSize(rPath string) (size int64, err error) {
+	err = filepath.Walk(rPath, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.Mode().IsRegular() {
+			size += info.Size()
+		}
+		return nil
+	})
+	return
+}
+
+// isPackageRepoName reports whether the dir name under the repo root
+// belongs to a live package repo.
+func isPackageRepoName(name string) bool {
+	if isDeletedRepoName(name) || isCorruptedRepoName(name) {
+		return false
+	}
+	return strings.HasSuffix(name, config.VCS.RepoExt)
+}
+
+func isDeletedRepoName(name string) bool {
+	return strings.HasSuffix(name, ".deleted")
+}
+
+func isCorruptedRepoName(name string) bool {
+	return strings.HasSuffix(name, ".corrupted")
+}
+
 func extractPkgName(rPath string) string {
 	repoName := filepath.Base(rPath)
 	ext := filepath.Ext(repoName)
 	return repoName[:(len(repoName) - len(ext))]
 }
 
-func packageRepoCloneOptions(pkgName string) (cloneOpt *git.CloneOptions, err error) {
-	rPath, err := packageRepoPath(pkgName)
-	if err != nil {
-		return
-	}
-
-	cloneOpt = &git.CloneOptions{
+func repoCloneOptions(rPath string) *git.CloneOptions {
+	return &git.CloneOptions{
 		URL:           rPath,
 		RemoteName:    "origin",
 		ReferenceName: plumbing.Master,
 		SingleBranch:  true,
 		Tags:          git.NoTags,
 	}
-
-	return
 }
 
 func exportPackageRepo(pkgName string) (err error) {
@@ -0,0 +1,77 @@
+package vcs
+
+import (
+	"testing"
+
+	"gopx.io/gopx-vcs-api/api/v1/constants"
+)
+
+func TestTagMessageRoundTrip(t *testing.T) {
+	sums := []tagTrailer{
+		{key: constants.TagTrailerGoSum, value: "h1:abc="},
+		{key: constants.TagTrailerGoModSum, value: "h1:def="},
+	}
+
+	cases := []struct {
+		name     string
+		notes    string
+		trailers []tagTrailer
+		sig      bool
+	}{
+		{name: "title only"},
+		{name: "trailers only", trailers: sums},
+		{name: "notes only", notes: "# Changes\n\n- Fixed the parser"},
+		{name: "notes and trailers", notes: "# Changes\n\n- Fixed the parser", trailers: sums},
+		{name: "notes with colons", notes: "Note:no space\nSee https://gopx.io", trailers: sums},
+		{name: "notes ending with trailer lines", notes: "# Changes\n\nBreaking: none\nSee-Also: v1.0.0", trailers: sums},
+		{name: "signed", notes: "# Changes", trailers: sums, sig: true},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			msg := vcsRepoTagMessage("v1.0.0", c.notes, c.trailers...)
+			if c.sig {
+				msg += sshSigBegin + "\nU1NIU0lH\n" + sshSigEnd + "\n"
+			}
+
+			if notes := tagMessageNotes(msg); notes != c.notes {
+				t.Errorf("got notes %q, want %q", notes, c.notes)
+			}
+
+			for _, tr := range c.trailers {
+				if v := tagMessageTrailer(msg, tr.key); v != tr.value {
+					t.Errorf("got %s trailer %q, want %q", tr.key, v, tr.value)
+				}
+			}
+
+			if v := tagMessageTrailer(msg, constants.TagTrailerIdempotencyKey); v != "" {
+				t.Errorf("got unset %s trailer %q", constants.TagTrailerIdempotencyKey, v)
+			}
+		})
+	}
+}
+
+func TestTagMessageTrailer(t *testing.T) {
+	cases := []struct {
+		name    string
+		message string
+		key     string
+		value   string
+	}{
+		{name: "exact key", message: "Released v1.0.0\n\nGo-Sum: h1:abc=\n", key: "Go-Sum", value: "h1:abc="},
+		{name: "key in other case", message: "Released v1.0.0\n\ngo-sum: h1:abc=\n", key: "Go-Sum", value: "h1:abc="},
+		{name: "missing key", message: "Released v1.0.0\n\nGo-Mod-Sum: h1:abc=\n", key: "Go-Sum"},
+		{name: "title only", message: "Go-Sum: h1:abc=\n", key: "Go-Sum"},
+		{name: "not a trailer paragraph", message: "Released v1.0.0\n\nGo-Sum: h1:abc=\nsome text\n", key: "Go-Sum"},
+		{name: "no space after colon", message: "Released v1.0.0\n\nGo-Sum:h1:abc=\n", key: "Go-Sum"},
+		{name: "space in key", message: "Released v1.0.0\n\nGo Sum: h1:abc=\n", key: "Go Sum"},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if v := tagMessageTrailer(c.message, c.key); v != c.value {
+				t.Errorf("got %q, want %q", v, c.value)
+			}
+		})
+	}
+}
@@ -0,0 +1,249 @@
+package vcs
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/sha512"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"strings"
+
+	"github.com/pkg/errors"
+	"golang.org/x/crypto/openpgp"
+	"golang.org/x/crypto/openpgp/armor"
+	"golang.org/x/crypto/ssh"
+	"gopkg.in/src-d/go-git.v4/plumbing"
+	"gopkg.in/src-d/go-git.v4/plumbing/object"
+	"gopx.io/gopx-vcs-api/api/v1/constants"
+	"gopx.io/gopx-vcs-api/pkg/config"
+)
+
+// sshSigNamespace is the sshsig namespace git uses for the signatures of the
+// commits and tags.
+const sshSigNamespace = "git"
+
+const (
+	sshSigBegin = "-----BEGIN SSH SIGNATURE-----"
+	sshSigEnd   = "-----END SSH SIGNATURE-----"
+)
+
+// signTag signs the release tag with the registry signing key, if one is
+// configured. The signature is appended to the tag message the same way as
+// git does, so that git tag -v can verify it.
+func signTag(tag *object.Tag) (err error) {
+	format := config.VCS.TagSignFormat
+	if format == "" {
+		return
+	}
+
+	if !strings.HasSuffix(tag.Message, "\n") {
+		tag.Message += "\n"
+	}
+
+	enObj := &plumbing.MemoryObject{}
+	err = tag.EncodeWithoutSignature(enObj)
+	if err != nil {
+		return
+	}
+
+	r, err := enObj.Reader()
+	if err != nil {
+		return
+	}
+	defer r.Close()
+
+	payload, err := ioutil.ReadAll(r)
+	if err != nil {
+		return
+	}
+
+	switch format {
+	case constants.TagSignFormatOpenPGP:
+		tag.PGPSignature, err = openPGPSign(payload)
+	case constants.TagSignFormatSSH:
+		tag.PGPSignature, err = sshSign(payload)
+	default:
+		err = errors.Errorf("Unknown tag signing format %s", format)
+	}
+
+	return
+}
+
+// SigningPublicKey returns the public key of the release tag signatures, as
+// an armored OpenPGP public key or as an allowed signers line of the
+// registry tagger for SSH signatures.
+func SigningPublicKey() (key []byte, format string, err error) {
+	format = config.VCS.TagSignFormat
+
+	switch format {
+	case constants.TagSignFormatOpenPGP:
+		var entity *openpgp.Entity
+		entity, err = openPGPSigningKey()
+		if err != nil {
+			return
+		}
+
+		buf := bytes.Buffer{}
+		var w io.WriteCloser
+		w, err = armor.Encode(&buf, openpgp.PublicKeyType, nil)
+		if err != nil {
+			return
+		}
+
+		err = entity.Serialize(w)
+		if err != nil {
+			return
+		}
+
+		err = w.Close()
+		if err != nil {
+			return
+		}
+
+		buf.WriteString("\n")
+		key = buf.Bytes()
+	case constants.TagSignFormatSSH:
+		var signer ssh.Signer
+		signer, err = sshSigningKey()
+		if err != nil {
+			return
+		}
+
+		authKey := strings.TrimSpace(string(ssh.MarshalAuthorizedKey(signer.PublicKey())))
+		key = []byte(fmt.Sprintf("%s namespaces=\"%s\" %s\n", constants.RepoAutoTaggerEmail, sshSigNamespace, authKey))
+	default:
+		err = constants.ErrSigningDisabled
+	}
+
+	return
+}
+
+func openPGPSigningKey() (entity *openpgp.Entity, err error) {
+	data, err := ioutil.ReadFile(config.VCS.TagSignKeyFile)
+	if err != nil {
+		err = errors.Wrap(err, "Couldn't read the tag signing key")
+		return
+	}
+
+	entities, err := openpgp.ReadArmoredKeyRing(bytes.NewReader(data))
+	if err != nil {
+		err = errors.Wrap(err, "Couldn't parse the tag signing key")
+		return
+	}
+
+	if len(entities) == 0 || entities[0].PrivateKey == nil {
+		err = errors.New("Tag signing key has no private key")
+		return
+	}
+
+	entity = entities[0]
+	return
+}
+
+func openPGPSign(payload []byte) (sig string, err error) {
+	entity, err := openPGPSigningKey()
+	if err != nil {
+		return
+	}
+
+	buf := bytes.Buffer{}
+	err = openpgp.ArmoredDetachSign(&buf, entity, bytes.NewReader(payload), nil)
+	if err != nil {
+		err = errors.Wrap(err, "Couldn't sign the tag")
+		return
+	}
+
+	sig = buf.String()
+	if !strings.HasSuffix(sig, "\n") {
+		sig += "\n"
+	}
+
+	return
+}
+
+func sshSigningKey() (signer ssh.Signer, err error) {
+	data, err := ioutil.ReadFile(config.VCS.TagSignKeyFile)
+	if err != nil {
+		err = errors.Wrap(err, "Couldn't read the tag signing key")
+		return
+	}
+
+	signer, err = ssh.ParsePrivateKey(data)
+	if err != nil {
+		err = errors.Wrap(err, "Couldn't parse the tag signing key")
+		return
+	}
+
+	return
+}
+
+// sshSign creates an armored sshsig signature of the payload, the format
+// ssh-keygen -Y sign writes and git verifies for the SSH signing keys.
+func sshSign(payload []byte) (sig string, err error) {
+	signer, err := sshSigningKey()
+	if err != nil {
+		return
+	}
+
+	hash := sha512.Sum512(payload)
+	signedData := ssh.Marshal(struct {
+		Magic     [6]byte
+		Namespace string
+		Reserved  string
+		HashAlg   string
+		Hash      string
+	}{sshSigMagic(), sshSigNamespace, "", "sha512", string(hash[:])})
+
+	var signature *ssh.Signature
+	if as, ok := signer.(ssh.AlgorithmSigner); ok && signer.PublicKey().Type() == ssh.KeyAlgoRSA {
+		// The SHA-1 RSA signatures are refused by ssh-keygen.
+		signature, err = as.SignWithAlgorithm(rand.Reader, signedData, ssh.KeyAlgoRSASHA512)
+	} else {
+		signature, err = signer.Sign(rand.Reader, signedData)
+	}
+	if err != nil {
+		err = errors.Wrap(err, "Couldn't sign the tag")
+		return
+	}
+
+	blob := ssh.Marshal(struct {
+		Magic     [6]byte
+		Version   uint32
+		PublicKey string
+		Namespace string
+		Reserved  string
+		HashAlg   string
+		Signature string
+	}{sshSigMagic(), 1, string(signer.PublicKey().Marshal()), sshSigNamespace, "", "sha512", string(ssh.Marshal(signature))})
+
+	enc := base64.StdEncoding.EncodeToString(blob)
+
+	buf := bytes.Buffer{}
+	buf.WriteString(sshSigBegin + "\n")
+	for len(enc) > 70 {
+		buf.WriteString(enc[:70] + "\n")
+		enc = enc[70:]
+	}
+	buf.WriteString(enc + "\n")
+	buf.WriteString(sshSigEnd + "\n")
+
+	sig = buf.String()
+	return
+}
+
+func sshSigMagic() (magic [6]byte) {
+	copy(magic[:], "SSHSIG")
+	return
+}
+
+// tagMessageBody returns the tag message without the SSH signature, which
+// is kept in the message as the tag decoder only splits off the OpenPGP
+// signatures.
+func tagMessageBody(message string) string {
+	if i := strings.Index(message, sshSigBegin); i >= 0 {
+		return message[:i]
+	}
+	return message
+}
@@ -0,0 +1,88 @@
+package vcs
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/Masterminds/semver"
+	"github.com/pkg/errors"
+	"gopx.io/gopx-vcs-api/api/v1/constants"
+)
+
+// versionTombstone records a deleted version of a package so that it
+// can never be published again.
+type versionTombstone struct {
+	Version   string    `json:"version"`
+	Tag       string    `json:"tag"`
+	Commit    string    `json:"commit"`
+	Tree      string    `json:"tree"`
+	DeletedAt time.Time `json:"deletedAt"`
+}
+
+func readVersionTombstones(rPath string) (tombstones []*versionTombstone, err error) {
+	data, err := ioutil.ReadFile(filepath.Join(rPath, constants.RepoTombstonesFileName))
+	if err != nil {
+		if os.IsNotExist(err) {
+			err = nil
+		}
+		return
+	}
+
+	err = json.Unmarshal(data, &tombstones)
+	if err != nil {
+		err = errors.Wrapf(err, "Invalid %s file [%s]", constants.RepoTombstonesFileName, extractPkgName(rPath))
+		return
+	}
+
+	return
+}
+
+func addVersionTombstone(rPath string, tombstone *versionTombstone) (err error) {
+	tombstones, err := readVersionTombstones(rPath)
+	if err != nil {
+		return
+	}
+
+	tombstones = append(tombstones, tombstone)
+
+	data, err := json.MarshalIndent(tombstones, "", "  ")
+	if err != nil {
+		return
+	}
+
+	err = ioutil.WriteFile(filepath.Join(rPath, constants.RepoTombstonesFileName), data, 0644)
+	if err != nil {
+		err = errors.Wrapf(err, "Unable to write %s file [%s]", constants.RepoTombstonesFileName, extractPkgName(rPath))
+		return
+	}
+
+	return
+}
+
+func isTombstonedVersion(rPath, version string) (ok bool, err error) {
+	v, err := semver.NewVersion(version)
+	if err != nil {
+		return
+	}
+
+	tombstones, err := readVersionTombstones(rPath)
+	if err != nil {
+		return
+	}
+
+	for _, t := range tombstones {
+		tv, err := semver.NewVersion(t.Version)
+		if err != nil {
+			return false, err
+		}
+
+		if v.Equal(tv) {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
@@ -0,0 +1,215 @@
+package vcs
+
+import (
+	"io"
+	"io/ioutil"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"gopkg.in/src-d/go-git.v4/plumbing/filemode"
+	"gopkg.in/src-d/go-git.v4/plumbing/object"
+	"gopx.io/gopx-vcs-api/api/v1/constants"
+	"gopx.io/gopx-vcs-api/api/v1/types"
+)
+
+// PackageFile represents a file content of a package version. The content
+// is read from the repo on demand, the file must be closed after use.
+type PackageFile struct {
+	Name    string
+	ModTime time.Time
+	Content io.ReadSeeker
+
+	blob *blobReader
+}
+
+// Close releases the reader of the file content.
+func (f *PackageFile) Close() error {
+	return f.blob.Close()
+}
+
+// PackageVersionTree lists the directory entries of a package version
+// at the specified path.
+func PackageVersionTree(pkgName, version, dirPath string) (entries []*types.TreeEntry, err error) {
+	_, commit, err := packageVersionCommit(pkgName, version)
+	if err != nil {
+		return
+	}
+
+	tree, err := commit.Tree()
+	if err != nil {
+		err = errors.Wrapf(err, "Couldn't access commit tree [%s]", pkgName)
+		return
+	}
+
+	dirPath = cleanTreePath(dirPath)
+	if dirPath != "" {
+		entry, err := tree.FindEntry(dirPath)
+		if err != nil || entry.Mode != filemode.Dir {
+			return nil, constants.ErrPathNotFound
+		}
+
+		tree, err = tree.Tree(dirPath)
+		if err != nil {
+			err = errors.Wrapf(err, "Couldn't access tree %s [%s]", dirPath, pkgName)
+			return nil, err
+		}
+	}
+
+	entries = make([]*types.TreeEntry, 0, len(tree.Entries))
+	for _, e := range tree.Entries {
+		entry := &types.TreeEntry{
+			Name: e.Name,
+			Path: path.Join(dirPath, e.Name),
+			Type: treeEntryType(e.Mode),
+			Mode: e.Mode.String(),
+			Hash: e.Hash.String(),
+		}
+
+		if e.Mode.IsFile() {
+			entry.Size, err = tree.Size(e.Name)
+			if err != nil {
+				err = errors.Wrapf(err, "Couldn't access file size of %s [%s]", entry.Path, pkgName)
+				return nil, err
+			}
+		}
+
+		entries = append(entries, entry)
+	}
+
+	return
+}
+
+// PackageVersionFile opens the file content of a package version at the
+// specified path.
+func PackageVersionFile(pkgName, version, filePath string) (file *PackageFile, err error) {
+	_, commit, err := packageVersionCommit(pkgName, version)
+	if err != nil {
+		return
+	}
+
+	filePath = cleanTreePath(filePath)
+	if filePath == "" {
+		err = constants.ErrPathNotFound
+		return
+	}
+
+	f, err := commit.File(filePath)
+	if err != nil {
+		if err == object.ErrFileNotFound {
+			err = constants.ErrPathNotFound
+			return
+		}
+		err = errors.Wrapf(err, "Couldn't access file %s [%s]", filePath, pkgName)
+		return
+	}
+
+	blob := &blobReader{blob: &f.Blob}
+	file = &PackageFile{
+		Name:    path.Base(filePath),
+		ModTime: commit.Committer.When,
+		Content: blob,
+		blob:    blob,
+	}
+
+	return
+}
+
+func cleanTreePath(p string) string {
+	p = path.Clean("/" + p)
+	return strings.TrimPrefix(p, "/")
+}
+
+func treeEntryType(mode filemode.FileMode) string {
+	switch mode {
+	case filemode.Dir:
+		return "dir"
+	case filemode.Symlink:
+		return "symlink"
+	case filemode.Submodule:
+		return "submodule"
+	default:
+		return "file"
+	}
+}
+
+// blobReader reads the blob content as an io.ReadSeeker without holding it
+// in memory. The blob is read again from the start on a backward seek.
+type blobReader struct {
+	blob *object.Blob
+	r    io.ReadCloser
+	pos  int64
+	off  int64
+}
+
+func (br *blobReader) Read(p []byte) (n int, err error) {
+	if br.off >= br.blob.Size {
+		return 0, io.EOF
+	}
+
+	if br.r == nil || br.off < br.pos {
+		err = br.reopen()
+		if err != nil {
+			return
+		}
+	}
+
+	if br.off > br.pos {
+		_, err = io.CopyN(ioutil.Discard, br.r, br.off-br.pos)
+		if err != nil {
+			return
+		}
+		br.pos = br.off
+	}
+
+	n, err = br.r.Read(p)
+	br.pos += int64(n)
+	br.off = br.pos
+	return
+}
+
+func (br *blobReader) Seek(offset int64, whence int) (int64, error) {
+	switch whence {
+	case io.SeekStart:
+	case io.SeekCurrent:
+		offset += br.off
+	case io.SeekEnd:
+		offset += br.blob.Size
+	default:
+		return 0, errors.Errorf("Invalid whence %d", whence)
+	}
+
+	if offset < 0 {
+		return 0, errors.Errorf("Negative position %d", offset)
+	}
+
+	br.off = offset
+	return offset, nil
+}
+
+func (br *blobReader) Close() error {
+	if br.r == nil {
+		return nil
+	}
+
+	err := br.r.Close()
+	br.r = nil
+	return err
+}
+
+func (br *blobReader) reopen() (err error) {
+	err = br.Close()
+	if err != nil {
+		return
+	}
+
+	br.r, err = br.blob.Reader()
+	if err != nil {
+		err = errors.Wrapf(err, "Couldn't read blob %s", br.blob.Hash)
+		return
+	}
+	br.pos = 0
+
+	return
+}
@@ -0,0 +1,350 @@
+package vcs
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"io"
+	"path"
+	"strings"
+
+	"github.com/pkg/errors"
+	"gopkg.in/src-d/go-billy.v4/osfs"
+	"gopkg.in/src-d/go-git.v4/plumbing"
+	"gopkg.in/src-d/go-git.v4/plumbing/cache"
+	"gopkg.in/src-d/go-git.v4/plumbing/format/pktline"
+	"gopkg.in/src-d/go-git.v4/plumbing/protocol/packp"
+	"gopkg.in/src-d/go-git.v4/plumbing/storer"
+	"gopkg.in/src-d/go-git.v4/plumbing/transport"
+	"gopkg.in/src-d/go-git.v4/plumbing/transport/server"
+	"gopkg.in/src-d/go-git.v4/storage/filesystem"
+	"gopx.io/gopx-vcs-api/api/v1/constants"
+	"gopx.io/gopx-vcs-api/pkg/config"
+)
+
+// UploadPackService is the name of the git service which serves fetches
+// and clones.
+const UploadPackService = "git-upload-pack"
+
+// UploadPackSession serves the git-upload-pack service of an exported
+// package repo.
+type UploadPackSession struct {
+	pkgName string
+	storer  storer.Storer
+	sess    transport.UploadPackSession
+}
+
+// UploadPackRequest represents a decoded git-upload-pack request. Done is
+// false while the client is still negotiating the common commits.
+type UploadPackRequest struct {
+	Done bool
+	req  *packp.UploadPackRequest
+}
+
+// PackageNameFromRepoPath returns the package name of a repo path requested
+// over the git, ssh and smart HTTP transports, e.g. "/foo.git" or "foo". The
+// repo extension is optional, the same as in git daemon. The paths of nested
+// dirs give an empty name.
+func PackageNameFromRepoPath(repoPath string) string {
+	p := strings.TrimPrefix(path.Clean("/"+repoPath), "/")
+	if strings.Contains(p, "/") {
+		return ""
+	}
+	return strings.TrimSuffix(p, config.VCS.RepoExt)
+}
+
+// NewUploadPackSession opens a git-upload-pack session on the package repo.
+// Only the repos which are exported are served.
+func NewUploadPackSession(pkgName string) (ups *UploadPackSession, err error) {
+	rPath, err := exportedPackageRepoPath(pkgName)
+	if err != nil {
+		return
+	}
+
+	ep, err := transport.NewEndpoint("/" + repoName(pkgName))
+	if err != nil {
+		err = errors.Wrapf(err, "Couldn't create repo endpoint [%s]", pkgName)
+		return
+	}
+
+	st := filesystem.NewStorage(osfs.New(rPath), cache.NewObjectLRUDefault())
+	srv := server.NewServer(server.MapLoader{ep.String(): st})
+	sess, err := srv.NewUploadPackSession(ep, nil)
+	if err != nil {
+		err = errors.Wrapf(err, "Couldn't open upload-pack session [%s]", pkgName)
+		return
+	}
+
+	ups = &UploadPackSession{
+		pkgName: pkgName,
+		storer:  st,
+		sess:    sess,
+	}
+
+	return
+}
+
+// AdvertisedRefs returns the refs advertisement of the package repo. The
+// service announcement expected by the smart HTTP clients is prepended
+// if smartHTTP is true.
+func (ups *UploadPackSession) AdvertisedRefs(smartHTTP bool) (ar *packp.AdvRefs, err error) {
+	ar, err = ups.sess.AdvertisedReferences()
+	if err != nil {
+		err = errors.Wrapf(err, "Couldn't advertise refs [%s]", ups.pkgName)
+		return
+	}
+
+	if smartHTTP {
+		ar.Prefix = [][]byte{
+			[]byte("# service=" + UploadPackService),
+			pktline.Flush,
+		}
+	}
+
+	return
+}
+
+// UploadPack validates the request and prepares its response. While the
+// client is still negotiating, the first common commit is acknowledged or a
+// NAK is sent if there is none. The packfile is sent once it is done.
+func (ups *UploadPackSession) UploadPack(ctx context.Context, upr *UploadPackRequest) (resp *UploadPackResponse, err error) {
+	haves := ups.knownHaves(upr.req.Haves)
+	upr.req.Haves = haves
+
+	if !upr.Done {
+		resp = &UploadPackResponse{}
+		if len(haves) > 0 {
+			resp.ack = &haves[0]
+		}
+		return
+	}
+
+	err = ups.checkWants(upr.req.Wants)
+	if err != nil {
+		return
+	}
+
+	packResp, err := ups.packfile(ctx, upr.req)
+	if err != nil {
+		return
+	}
+
+	if len(haves) > 0 {
+		packResp.ACKs = []plumbing.Hash{haves[len(haves)-1]}
+	}
+
+	resp = &UploadPackResponse{resp: packResp}
+	return
+}
+
+// Serve serves a whole upload-pack conversation in the stateful mode, as
+// spoken over the git and ssh transports. The refs are advertised, the
+// common commits are negotiated and the packfile is sent over the same
+// connection.
+func (ups *UploadPackSession) Serve(ctx context.Context, r io.Reader, w io.Writer) (err error) {
+	ar, err := ups.AdvertisedRefs(false)
+	if err != nil {
+		return
+	}
+
+	err = ar.Encode(w)
+	if err != nil {
+		return
+	}
+
+	// A client which is up to date sends a flush-pkt instead of the wants.
+	br := bufio.NewReader(r)
+	start, err := br.Peek(4)
+	if err == io.EOF {
+		err = nil
+		return
+	}
+	if err != nil {
+		return
+	}
+	if bytes.Equal(start, pktline.FlushPkt) {
+		return
+	}
+
+	req := packp.NewUploadPackRequest()
+	if err = req.Decode(br); err != nil {
+		err = errors.Wrap(constants.ErrInvalidUploadPack, err.Error())
+		return
+	}
+
+	err = ups.checkWants(req.Wants)
+	if err != nil {
+		return
+	}
+
+	// Without multi_ack only the first common commit is acknowledged, and a
+	// NAK is sent at the end of each round of haves until then.
+	e := pktline.NewEncoder(w)
+	s := pktline.NewScanner(br)
+	acked := false
+	for done := false; !done; {
+		if !s.Scan() {
+			err = s.Err()
+			if err == nil {
+				err = io.ErrUnexpectedEOF
+			}
+			err = errors.Wrap(constants.ErrInvalidUploadPack, err.Error())
+			return
+		}
+
+		line := bytes.TrimSuffix(s.Bytes(), []byte("\n"))
+		switch {
+		case len(line) == 0:
+			if !acked {
+				err = e.EncodeString("NAK\n")
+			}
+		case bytes.Equal(line, []byte("done")):
+			if !acked {
+				err = e.EncodeString("NAK\n")
+			}
+			done = true
+		case bytes.HasPrefix(line, []byte("have ")):
+			haves := ups.knownHaves([]plumbing.Hash{plumbing.NewHash(string(line[len("have "):]))})
+			req.Haves = append(req.Haves, haves...)
+			if len(haves) > 0 && !acked {
+				err = e.Encodef("ACK %s\n", haves[0])
+				acked = true
+			}
+		default:
+			err = errors.Wrapf(constants.ErrInvalidUploadPack, "Unexpected line %q", line)
+		}
+
+		if err != nil {
+			return
+		}
+	}
+
+	packResp, err := ups.packfile(ctx, req)
+	if err != nil {
+		return
+	}
+	defer packResp.Close()
+
+	_, err = io.Copy(w, packResp)
+	return
+}
+
+// knownHaves returns the haves which are present in the repo, as the unknown
+// ones can't be excluded from the packfile.
+func (ups *UploadPackSession) knownHaves(haves []plumbing.Hash) (known []plumbing.Hash) {
+	for _, have := range haves {
+		if ups.storer.HasEncodedObject(have) == nil {
+			known = append(known, have)
+		}
+	}
+	return
+}
+
+// checkWants makes sure only the advertised refs are requested, so that the
+// unpublished versions can't be fetched by their hashes.
+func (ups *UploadPackSession) checkWants(wants []plumbing.Hash) (err error) {
+	ar, err := ups.sess.AdvertisedReferences()
+	if err != nil {
+		err = errors.Wrapf(err, "Couldn't read refs [%s]", ups.pkgName)
+		return
+	}
+
+	tips := map[plumbing.Hash]bool{}
+	for _, h := range ar.References {
+		tips[h] = true
+	}
+
+	for _, want := range wants {
+		if !tips[want] {
+			err = errors.Wrapf(constants.ErrInvalidUploadPack, "Object %s is not advertised", want)
+			return
+		}
+	}
+
+	return
+}
+
+func (ups *UploadPackSession) packfile(ctx context.Context, req *packp.UploadPackRequest) (resp *packp.UploadPackResponse, err error) {
+	resp, err = ups.sess.UploadPack(ctx, req)
+	if err == transport.ErrEmptyUploadPackRequest {
+		err = errors.Wrap(constants.ErrInvalidUploadPack, err.Error())
+		return
+	}
+	if err != nil {
+		err = errors.Wrapf(err, "Couldn't build the packfile [%s]", ups.pkgName)
+		return
+	}
+
+	return
+}
+
+// Close closes the session.
+func (ups *UploadPackSession) Close() error {
+	return ups.sess.Close()
+}
+
+// UploadPackResponse represents the response of a git-upload-pack request.
+type UploadPackResponse struct {
+	ack  *plumbing.Hash
+	resp *packp.UploadPackResponse
+}
+
+// Encode writes the response to w.
+func (pr *UploadPackResponse) Encode(w io.Writer) (err error) {
+	if pr.resp == nil {
+		sr := &packp.ServerResponse{}
+		if pr.ack != nil {
+			sr.ACKs = []plumbing.Hash{*pr.ack}
+		}
+		err = sr.Encode(w)
+		return
+	}
+
+	err = pr.resp.Encode(w)
+	return
+}
+
+// Close releases the packfile of the response if it wasn't sent.
+func (pr *UploadPackResponse) Close() error {
+	if pr.resp == nil {
+		return nil
+	}
+	return pr.resp.Close()
+}
+
+// DecodeUploadPackRequest decodes the wants and haves of an upload-pack
+// request sent in the stateless mode.
+func DecodeUploadPackRequest(r io.Reader) (upr *UploadPackRequest, err error) {
+	req := packp.NewUploadPackRequest()
+	if err = req.Decode(r); err != nil {
+		err = errors.Wrap(constants.ErrInvalidUploadPack, err.Error())
+		return
+	}
+
+	upr = &UploadPackRequest{req: req}
+
+	s := pktline.NewScanner(r)
+	for s.Scan() {
+		line := bytes.TrimSuffix(s.Bytes(), []byte("\n"))
+		switch {
+		case len(line) == 0:
+			// Flush-pkt terminating a round of haves.
+		case bytes.Equal(line, []byte("done")):
+			upr.Done = true
+			return
+		case bytes.HasPrefix(line, []byte("have ")):
+			h := plumbing.NewHash(string(line[len("have "):]))
+			req.Haves = append(req.Haves, h)
+		default:
+			err = errors.Wrapf(constants.ErrInvalidUploadPack, "Unexpected line %q", line)
+			return
+		}
+	}
+
+	if err = s.Err(); err != nil {
+		err = errors.Wrap(constants.ErrInvalidUploadPack, err.Error())
+		return
+	}
+
+	return
+}
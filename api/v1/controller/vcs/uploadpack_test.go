@@ -0,0 +1,146 @@
+package vcs
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/pkg/errors"
+	git "gopkg.in/src-d/go-git.v4"
+	"gopkg.in/src-d/go-git.v4/plumbing"
+	"gopkg.in/src-d/go-git.v4/plumbing/format/pktline"
+	"gopx.io/gopx-vcs-api/api/v1/constants"
+	"gopx.io/gopx-vcs-api/pkg/config"
+)
+
+// pktLines encodes the lines as pkt-lines, the empty lines as flush-pkts.
+func pktLines(t *testing.T, lines ...string) []byte {
+	buf := &bytes.Buffer{}
+	e := pktline.NewEncoder(buf)
+
+	for _, l := range lines {
+		var err error
+		if l == "" {
+			err = e.Flush()
+		} else {
+			err = e.EncodeString(l)
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	return buf.Bytes()
+}
+
+func TestUploadPackSessionServe(t *testing.T) {
+	saved := config.VCS.RepoRoot
+	defer func() { config.VCS.RepoRoot = saved }()
+
+	root, err := ioutil.TempDir("", "gopx-uploadpack-test-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(root)
+	config.VCS.RepoRoot = root
+
+	work := newTaggedRepo(t, "1.0.0", "1.1.0")
+	defer os.RemoveAll(work)
+
+	repo, err := git.PlainOpen(work)
+	if err != nil {
+		t.Fatal(err)
+	}
+	head, err := repo.Head()
+	if err != nil {
+		t.Fatal(err)
+	}
+	first, err := repoVersionTag(repo, "1.0.0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The git dir of the work tree is a bare repo on its own.
+	rPath, err := packageRepoPath("foo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = os.Rename(filepath.Join(work, ".git"), rPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = makeVisibleRepo(rPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := "want " + head.Hash().String() + "\n"
+	cases := []struct {
+		name  string
+		input []byte
+		err   error
+		out   []string
+	}{
+		{name: "closed input", out: []string{head.Hash().String()}},
+		{name: "up to date client", input: pktLines(t, ""), out: []string{head.Hash().String()}},
+		{name: "clone", input: pktLines(t, want, "", "done\n"), out: []string{"NAK", "PACK"}},
+		{name: "fetch", input: pktLines(t, want, "", "have "+first.Target.String()+"\n", "done\n"), out: []string{"ACK " + first.Target.String(), "PACK"}},
+		{name: "unadvertised want", input: pktLines(t, "want "+plumbing.ComputeHash(plumbing.BlobObject, []byte("x")).String()+"\n", ""), err: constants.ErrInvalidUploadPack},
+		{name: "truncated negotiation", input: pktLines(t, want, ""), err: constants.ErrInvalidUploadPack},
+		{name: "unexpected line", input: pktLines(t, want, "", "deepen 1\n"), err: constants.ErrInvalidUploadPack},
+		{name: "not a pkt-line", input: []byte("zzzz"), err: constants.ErrInvalidUploadPack},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			ups, err := NewUploadPackSession("foo")
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer ups.Close()
+
+			out := &bytes.Buffer{}
+			err = ups.Serve(context.Background(), bytes.NewReader(c.input), out)
+			if errors.Cause(err) != c.err {
+				t.Fatalf("got error %v, want %v", err, c.err)
+			}
+
+			for _, s := range c.out {
+				if !bytes.Contains(out.Bytes(), []byte(s)) {
+					t.Errorf("output doesn't contain %q", s)
+				}
+			}
+		})
+	}
+}
+
+func TestPackageNameFromRepoPath(t *testing.T) {
+	saved := config.VCS.RepoExt
+	defer func() { config.VCS.RepoExt = saved }()
+	config.VCS.RepoExt = ".git"
+
+	cases := []struct {
+		repoPath string
+		pkgName  string
+	}{
+		{repoPath: "/foo.git", pkgName: "foo"},
+		{repoPath: "foo.git", pkgName: "foo"},
+		{repoPath: "/foo", pkgName: "foo"},
+		{repoPath: "/foo.git/", pkgName: "foo"},
+		{repoPath: "/./foo.git", pkgName: "foo"},
+		{repoPath: "/bar/../foo.git", pkgName: "foo"},
+		{repoPath: "/../foo.git", pkgName: "foo"},
+		{repoPath: "/bar/foo.git", pkgName: ""},
+		{repoPath: "/", pkgName: ""},
+		{repoPath: "", pkgName: ""},
+	}
+
+	for _, c := range cases {
+		if pkgName := PackageNameFromRepoPath(c.repoPath); pkgName != c.pkgName {
+			t.Errorf("got package name %q of %q, want %q", pkgName, c.repoPath, c.pkgName)
+		}
+	}
+}
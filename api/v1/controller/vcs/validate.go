@@ -0,0 +1,216 @@
+package vcs
+
+import (
+	"fmt"
+	"go/parser"
+	"go/token"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/Masterminds/semver"
+	"github.com/pkg/errors"
+	"golang.org/x/mod/modfile"
+	"golang.org/x/mod/module"
+	modsemver "golang.org/x/mod/semver"
+	modzip "golang.org/x/mod/zip"
+	"gopx.io/gopx-vcs-api/api/v1/constants"
+	"gopx.io/gopx-vcs-api/api/v1/types"
+	"gopx.io/gopx-vcs-api/pkg/config"
+)
+
+// packageModulePath returns the Go module path of a package version, which
+// is the import path prefix followed by the package name, and the major
+// version suffix for v2 and above.
+func packageModulePath(pkgName, pkgVersion string) (modPath string, err error) {
+	v, err := semver.NewVersion(pkgVersion)
+	if err != nil {
+		return
+	}
+
+	modPath = strings.TrimSuffix(config.VCS.ImportPathPrefix, "/") + "/" + pkgName
+	if v.Major() >= 2 {
+		modPath = fmt.Sprintf("%s/v%d", modPath, v.Major())
+	}
+
+	return
+}
+
+// validatePackageVersion checks that the version to publish is a full
+// semantic version, MAJOR.MINOR.PATCH with an optional prerelease, and
+// optionally prefixed with v. The build metadata is refused, as neither the
+// release tags nor the Go module versions can carry it. The errors are
+// reported with ErrInvalidVersion.
+func validatePackageVersion(pkgVersion string) error {
+	v := "v" + strings.TrimPrefix(pkgVersion, "v")
+
+	if !modsemver.IsValid(v) {
+		return errors.Wrapf(constants.ErrInvalidVersion, "Version %s is not a valid semantic version", pkgVersion)
+	}
+
+	if modsemver.Build(v) != "" {
+		return errors.Wrapf(constants.ErrInvalidVersion, "Version %s has build metadata, which is not supported", pkgVersion)
+	}
+
+	if modsemver.Canonical(v) != v {
+		return errors.Wrapf(constants.ErrInvalidVersion, "Version %s must be in the MAJOR.MINOR.PATCH form", pkgVersion)
+	}
+
+	return nil
+}
+
+// normalizeReleaseNotes returns the release notes with the LF line endings
+// and without the surrounding blank space, and checks that they can be
+// stored in the release tag message. Lines looking like the start of a
+// signature are refused, as git and the tag decoder would take the rest of
+// the message as the tag signature. The errors are reported with
+// ErrInvalidNotes.
+func normalizeReleaseNotes(notes string) (string, error) {
+	if !utf8.ValidString(notes) || strings.ContainsRune(notes, 0) {
+		return "", errors.Wrap(constants.ErrInvalidNotes, "Release notes must be valid UTF-8 text")
+	}
+
+	notes = strings.Replace(notes, "\r\n", "\n", -1)
+	notes = strings.TrimSpace(notes)
+
+	for _, line := range strings.Split(notes, "\n") {
+		if strings.HasPrefix(line, "-----BEGIN ") {
+			return "", errors.Wrap(constants.ErrInvalidNotes, "Release notes can't contain a line starting with -----BEGIN")
+		}
+	}
+
+	return notes, nil
+}
+
+// validatePackageModule checks that the extracted package data in dir is a
+// Go module which go get accepts for the package version: the go.mod at the
+// root must declare the module path of the package, all the Go source files
+// must parse, and the files must fit in a module zip. The errors are
+// reported with ErrInvalidModule.
+func validatePackageModule(dir string, meta *types.PackageMeta) (err error) {
+	modPath, err := packageModulePath(meta.Name, meta.Version)
+	if err != nil {
+		return
+	}
+
+	err = module.CheckPath(modPath)
+	if err != nil {
+		err = errors.Wrapf(constants.ErrInvalidModule, "Package name %s can't be used in a module path: %s", meta.Name, err)
+		return
+	}
+
+	err = validateGoMod(dir, modPath)
+	if err != nil {
+		return
+	}
+
+	err = validateGoFiles(dir)
+	if err != nil {
+		return
+	}
+
+	err = validateModuleZipFiles(dir)
+	return
+}
+
+func validateGoMod(dir, modPath string) (err error) {
+	fi, err := os.Lstat(filepath.Join(dir, "go.mod"))
+	if os.IsNotExist(err) {
+		err = errors.Wrap(constants.ErrInvalidModule, "go.mod not found at the package root")
+		return
+	}
+	if err != nil {
+		return
+	}
+
+	if !fi.Mode().IsRegular() {
+		err = errors.Wrap(constants.ErrInvalidModule, "go.mod is not a regular file")
+		return
+	}
+
+	data, err := ioutil.ReadFile(filepath.Join(dir, "go.mod"))
+	if err != nil {
+		return
+	}
+
+	f, err := modfile.Parse("go.mod", data, nil)
+	if err != nil {
+		err = errors.Wrap(constants.ErrInvalidModule, err.Error())
+		return
+	}
+
+	if f.Module == nil {
+		err = errors.Wrap(constants.ErrInvalidModule, "go.mod has no module directive")
+		return
+	}
+
+	if f.Module.Mod.Path != modPath {
+		err = errors.Wrapf(constants.ErrInvalidModule, "go.mod declares module %s, expected %s", f.Module.Mod.Path, modPath)
+		return
+	}
+
+	return
+}
+
+// validateGoFiles parses the Go source files in dir. The dirs ignored by the
+// go tool, testdata and the ones starting with . or _, are skipped.
+func validateGoFiles(dir string) error {
+	fset := token.NewFileSet()
+
+	return filepath.Walk(dir, func(p string, fi os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		rel, err := filepath.Rel(dir, p)
+		if err != nil {
+			return err
+		}
+
+		if fi.IsDir() {
+			name := fi.Name()
+			if rel != "." && (name == "testdata" || strings.HasPrefix(name, ".") || strings.HasPrefix(name, "_")) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		if !fi.Mode().IsRegular() || filepath.Ext(p) != ".go" {
+			return nil
+		}
+
+		src, err := ioutil.ReadFile(p)
+		if err != nil {
+			return err
+		}
+
+		// The relative name keeps the server paths out of the errors.
+		_, err = parser.ParseFile(fset, filepath.ToSlash(rel), src, 0)
+		if err != nil {
+			return errors.Wrap(constants.ErrInvalidModule, err.Error())
+		}
+
+		return nil
+	})
+}
+
+// validateModuleZipFiles checks that the files in dir can be served as a
+// module zip, e.g. there are no file names colliding on case-insensitive file
+// systems and the size is within the module zip limits.
+func validateModuleZipFiles(dir string) error {
+	cf, err := modzip.CheckDir(dir)
+	if err == nil {
+		return nil
+	}
+
+	cfErr := cf.Err()
+	if cfErr == nil {
+		return err
+	}
+
+	// The paths are reported relative to the package root.
+	msg := strings.Replace(cfErr.Error(), dir+string(filepath.Separator), "", -1)
+	return errors.Wrap(constants.ErrInvalidModule, msg)
+}
@@ -0,0 +1,317 @@
+package vcs
+
+import (
+	"io"
+	"sort"
+	"time"
+
+	"github.com/Masterminds/semver"
+	"github.com/pkg/errors"
+	git "gopkg.in/src-d/go-git.v4"
+	"gopkg.in/src-d/go-git.v4/plumbing"
+	"gopkg.in/src-d/go-git.v4/plumbing/object"
+	"gopx.io/gopx-vcs-api/api/v1/constants"
+	"gopx.io/gopx-vcs-api/api/v1/types"
+)
+
+// packageTag represents a release tag of a package repo along with
+// its parsed version.
+type packageTag struct {
+	version *semver.Version
+	tag     *object.Tag
+}
+
+// PackageVersions returns the published versions of a package sorted
+// from the newest to the oldest one.
+func PackageVersions(pkgName string) (versions []*types.PackageVersion, err error) {
+	rPath, err := lookupPackageRepoPath(pkgName)
+	if err != nil {
+		return
+	}
+
+	repo, err := git.PlainOpen(rPath)
+	if err != nil {
+		err = errors.Wrapf(err, "Package repo couldn't be opened [%s]", pkgName)
+		return
+	}
+
+	tags, err := packageRepoTags(repo)
+	if err != nil {
+		err = errors.Wrapf(err, "Couldn't read release tags [%s]", pkgName)
+		return
+	}
+
+	versions = make([]*types.PackageVersion, 0, len(tags))
+	for _, t := range tags {
+		versions = append(versions, t.packageVersion())
+	}
+
+	return
+}
+
+// packageVersion returns the published version represented by the release
+// tag. The go.sum hashes are taken from the tag message trailers.
+func (t *packageTag) packageVersion() *types.PackageVersion {
+	return &types.PackageVersion{
+		Version: t.version.String(),
+		Tag:     t.tag.Name,
+		Commit:  t.tag.Target.String(),
+		Date:    t.tag.Tagger.When,
+		Tagger: types.PackageTagger{
+			Name:  t.tag.Tagger.Name,
+			Email: t.tag.Tagger.Email,
+		},
+		Sum:      tagMessageTrailer(t.tag.Message, constants.TagTrailerGoSum),
+		GoModSum: tagMessageTrailer(t.tag.Message, constants.TagTrailerGoModSum),
+	}
+}
+
+// PackageVersionNotes returns the release notes of a package version, which
+// are empty if none were given on publish.
+func PackageVersionNotes(pkgName, version string) (notes *types.PackageReleaseNotes, err error) {
+	rPath, err := lookupPackageRepoPath(pkgName)
+	if err != nil {
+		return
+	}
+
+	repo, err := git.PlainOpen(rPath)
+	if err != nil {
+		err = errors.Wrapf(err, "Package repo couldn't be opened [%s]", pkgName)
+		return
+	}
+
+	tag, err := repoVersionTag(repo, version)
+	if err != nil {
+		err = errors.Wrapf(err, "Failed to resolve the release tag of %s [%s]", version, pkgName)
+		return
+	}
+
+	tagVer, err := semver.NewVersion(tag.Name)
+	if err != nil {
+		return
+	}
+
+	notes = &types.PackageReleaseNotes{
+		Version: tagVer.String(),
+		Tag:     tag.Name,
+		Notes:   tagMessageNotes(tag.Message),
+	}
+
+	return
+}
+
+// packageVersionCommit resolves the release tag of the package version
+// and the commit it points to.
+func packageVersionCommit(pkgName, version string) (tag *object.Tag, commit *object.Commit, err error) {
+	rPath, err := lookupPackageRepoPath(pkgName)
+	if err != nil {
+		return
+	}
+
+	repo, err := git.PlainOpen(rPath)
+	if err != nil {
+		err = errors.Wrapf(err, "Package repo couldn't be opened [%s]", pkgName)
+		return
+	}
+
+	tag, err = repoVersionTag(repo, version)
+	if err != nil {
+		err = errors.Wrapf(err, "Failed to resolve the release tag of %s [%s]", version, pkgName)
+		return
+	}
+
+	commit, err = tag.Commit()
+	if err != nil {
+		err = errors.Wrapf(err, "Couldn't access tagged commit %s [%s]", tag.Name, pkgName)
+		return
+	}
+
+	return
+}
+
+// repoVersionTag returns the release tag object of the version.
+func repoVersionTag(repo *git.Repository, version string) (tag *object.Tag, err error) {
+	tagName, err := tagNameFromVersion(version)
+	if err != nil {
+		err = constants.ErrVersionNotFound
+		return
+	}
+
+	ref, err := repo.Tag(tagName)
+	if err != nil {
+		if err == git.ErrTagNotFound {
+			err = constants.ErrVersionNotFound
+			return
+		}
+		err = errors.Wrapf(err, "Couldn't access tag reference %s", tagName)
+		return
+	}
+
+	tag, err = repo.TagObject(ref.Hash())
+	if err != nil {
+		err = errors.Wrapf(err, "Couldn't access tag object %s", tagName)
+		return
+	}
+
+	return
+}
+
+// DeletePackageVersion unpublishes a single version of a package. The release
+// tag is removed, master is moved back to the highest remaining version if
+// it pointed to the deleted one, and a tombstone is left behind so that
+// the version can't be published again.
+func DeletePackageVersion(pkgName, version string) (err error) {
+	rPath, err := lookupPackageRepoPath(pkgName)
+	if err != nil {
+		return
+	}
+
+	repo, err := git.PlainOpen(rPath)
+	if err != nil {
+		err = errors.Wrapf(err, "Package repo couldn't be opened [%s]", pkgName)
+		return
+	}
+
+	tag, err := repoVersionTag(repo, version)
+	if err != nil {
+		err = errors.Wrapf(err, "Failed to resolve the release tag of %s [%s]", version, pkgName)
+		return
+	}
+
+	tags, err := packageRepoTags(repo)
+	if err != nil {
+		err = errors.Wrapf(err, "Couldn't read release tags [%s]", pkgName)
+		return
+	}
+
+	// The master follows the latest version, so it moves back to the highest
+	// of the remaining versions.
+	var prev *packageTag
+	for _, t := range tags {
+		if t.tag.Name != tag.Name {
+			prev = t
+			break
+		}
+	}
+
+	if prev == nil {
+		err = constants.ErrLastVersion
+		return
+	}
+
+	commit, err := tag.Commit()
+	if err != nil {
+		err = errors.Wrapf(err, "Couldn't access tagged commit %s [%s]", tag.Name, pkgName)
+		return
+	}
+
+	// The tag is removed first so that a failed removal doesn't leave a
+	// tombstone of a version which is still published. The tag is put back
+	// if the tombstone can't be recorded.
+	tagRefName := plumbing.NewTagReferenceName(tag.Name)
+	err = repo.Storer.RemoveReference(tagRefName)
+	if err != nil {
+		err = errors.Wrapf(err, "Couldn't remove tag reference %s [%s]", tag.Name, pkgName)
+		return
+	}
+
+	err = addVersionTombstone(rPath, &versionTombstone{
+		Version:   version,
+		Tag:       tag.Name,
+		Commit:    commit.Hash.String(),
+		Tree:      commit.TreeHash.String(),
+		DeletedAt: time.Now(),
+	})
+	if err != nil {
+		err = errors.Wrapf(err, "Failed to record tombstone of %s [%s]", tag.Name, pkgName)
+
+		rollbackErr := repo.Storer.SetReference(plumbing.NewHashReference(tagRefName, tag.Hash))
+		if rollbackErr != nil {
+			err = errors.Wrapf(err, "Couldn't restore tag reference %s: %s", tag.Name, rollbackErr)
+		}
+		return
+	}
+
+	masterRef, err := repo.Reference(plumbing.Master, true)
+	if err != nil {
+		err = errors.Wrapf(err, "Couldn't access master reference [%s]", pkgName)
+		return
+	}
+
+	if masterRef.Hash() != commit.Hash {
+		return
+	}
+
+	err = repo.Storer.SetReference(plumbing.NewHashReference(plumbing.Master, prev.tag.Target))
+	if err != nil {
+		err = errors.Wrapf(err, "Couldn't move master to %s [%s]", prev.tag.Name, pkgName)
+		return
+	}
+
+	return
+}
+
+// packageRepoTags returns the annotated release tags referenced from the
+// repo sorted from the newest to the oldest version.
+func packageRepoTags(repo *git.Repository) (tags []*packageTag, err error) {
+	refIter, err := repo.Tags()
+	if err != nil {
+		err = errors.Wrap(err, "Couldn't access tag references")
+		return
+	}
+	defer refIter.Close()
+
+	for {
+		ref, err := refIter.Next()
+		if err != nil {
+			if err == io.EOF {
+				break
+			}
+			err = errors.Wrap(err, "Couldn't iterate over tag references")
+			return nil, err
+		}
+
+		tag, err := repo.TagObject(ref.Hash())
+		if err != nil {
+			if err == plumbing.ErrObjectNotFound {
+				// Lightweight tags are never created by the registry.
+				continue
+			}
+			err = errors.Wrapf(err, "Couldn't access tag object %s", ref.Name().Short())
+			return nil, err
+		}
+
+		tagVer, err := semver.NewVersion(tag.Name)
+		if err != nil {
+			return nil, err
+		}
+
+		tags = append(tags, &packageTag{version: tagVer, tag: tag})
+	}
+
+	sort.Slice(tags, func(i, j int) bool {
+		return tags[i].version.GreaterThan(tags[j].version)
+	})
+
+	return
+}
+
+// tagPublishedAfter reports whether the release tag a was published after b.
+// Tags published within the same second are ordered by their versions.
+func tagPublishedAfter(a, b *object.Tag) bool {
+	if !a.Tagger.When.Equal(b.Tagger.When) {
+		return a.Tagger.When.After(b.Tagger.When)
+	}
+
+	av, err := semver.NewVersion(a.Name)
+	if err != nil {
+		return false
+	}
+
+	bv, err := semver.NewVersion(b.Name)
+	if err != nil {
+		return false
+	}
+
+	return av.GreaterThan(bv)
+}
@@ -0,0 +1,107 @@
+package vcs
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	git "gopkg.in/src-d/go-git.v4"
+	"gopkg.in/src-d/go-git.v4/plumbing"
+	"gopx.io/gopx-vcs-api/api/v1/constants"
+	"gopx.io/gopx-vcs-api/pkg/config"
+)
+
+func TestDeletePackageVersionAfterBackport(t *testing.T) {
+	saved := config.VCS.RepoRoot
+	defer func() { config.VCS.RepoRoot = saved }()
+
+	root, err := ioutil.TempDir("", "gopx-version-test-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(root)
+	config.VCS.RepoRoot = root
+
+	work := newTaggedRepo(t, "1.0.0", "1.1.0", "1.2.0")
+	defer os.RemoveAll(work)
+
+	repo, err := git.PlainOpen(work)
+	if err != nil {
+		t.Fatal(err)
+	}
+	latest, err := repo.Head()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The backport is published last, but master stays at the latest version.
+	err = ioutil.WriteFile(filepath.Join(work, "version"), []byte("1.0.1"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wt, err := repo.Worktree()
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = wt.Add("version")
+	if err != nil {
+		t.Fatal(err)
+	}
+	sig := vcsRepoTaggerSignature()
+	sig.When = sig.When.Add(time.Hour)
+	hash, err := wt.Commit("1.0.1", &git.CommitOptions{Author: sig})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = repo.CreateTag("v1.0.1", hash, &git.CreateTagOptions{Tagger: sig, Message: "Released v1.0.1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = repo.Storer.SetReference(plumbing.NewHashReference(plumbing.Master, latest.Hash()))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rPath, err := packageRepoPath("foo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = os.Rename(filepath.Join(work, ".git"), rPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = DeletePackageVersion("foo", "1.2.0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	repo, err = git.PlainOpen(rPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	master, err := repo.Reference(plumbing.Master, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want, err := repoVersionTag(repo, "1.1.0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if master.Hash() != want.Target {
+		t.Errorf("got master at %s, want %s of v1.1.0", master.Hash(), want.Target)
+	}
+
+	_, err = repoVersionTag(repo, "1.2.0")
+	if errors.Cause(err) != constants.ErrVersionNotFound {
+		t.Errorf("got error %v for the deleted version, want %v", err, constants.ErrVersionNotFound)
+	}
+
+	ok, err := isTombstonedVersion(rPath, "1.2.0")
+	if err != nil || !ok {
+		t.Errorf("got tombstone %t and error %v, want the deleted version tombstoned", ok, err)
+	}
+}
@@ -0,0 +1,125 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"github.com/pkg/errors"
+	"gopx.io/gopx-common/log"
+	"gopx.io/gopx-vcs-api/api/v1/constants"
+	"gopx.io/gopx-vcs-api/api/v1/controller/helper"
+	"gopx.io/gopx-vcs-api/api/v1/controller/vcs"
+	errorCtrl "gopx.io/gopx-vcs-api/pkg/controller/error"
+)
+
+// PackageSnapshotsGET lists the soft deleted snapshots of a package.
+// Request: GET /admin/packages/:packageName/snapshots
+func PackageSnapshotsGET(w http.ResponseWriter, r *http.Request) {
+	if !authenticate(w, r) {
+		return
+	}
+
+	inputPkgName := mux.Vars(r)["packageName"]
+
+	snapshots, err := vcs.DeletedPackageSnapshots(inputPkgName)
+	if err != nil {
+		log.Error("Error %s", err)
+		errorCtrl.Error500(w, r)
+		return
+	}
+
+	helper.WriteResponseValueOK(w, r, snapshots)
+}
+
+// PackageSnapshotRestorePOST restores a soft deleted snapshot of a package.
+// Request: POST /admin/packages/:packageName/snapshots/:snapshotID/restore
+func PackageSnapshotRestorePOST(w http.ResponseWriter, r *http.Request) {
+	if !authenticate(w, r) {
+		return
+	}
+
+	vars := mux.Vars(r)
+	inputPkgName := vars["packageName"]
+
+	err := vcs.RestorePackage(inputPkgName, vars["snapshotID"])
+	if err != nil {
+		switch errors.Cause(err) {
+		case constants.ErrSnapshotNotFound:
+			errorCtrl.Error404(w, r)
+		case constants.ErrPackageExists:
+			errorCtrl.Error(w, r, http.StatusConflict, "A live package with the same name already exists")
+		default:
+			log.Error("Error %s", err)
+			errorCtrl.Error500(w, r)
+		}
+		return
+	}
+
+	helper.WriteResponse(w, r, nil, http.StatusNoContent)
+}
+
+// QuarantinedReposGET lists the quarantined repos.
+// Request: GET /admin/quarantine
+func QuarantinedReposGET(w http.ResponseWriter, r *http.Request) {
+	if !authenticate(w, r) {
+		return
+	}
+
+	reports, err := vcs.QuarantinedRepos()
+	if err != nil {
+		log.Error("Error %s", err)
+		errorCtrl.Error500(w, r)
+		return
+	}
+
+	helper.WriteResponseValueOK(w, r, reports)
+}
+
+// QuarantinedRepoGET returns the diagnostic report of a quarantined repo.
+// Request: GET /admin/quarantine/:quarantineID
+func QuarantinedRepoGET(w http.ResponseWriter, r *http.Request) {
+	if !authenticate(w, r) {
+		return
+	}
+
+	report, err := vcs.QuarantinedRepo(mux.Vars(r)["quarantineID"])
+	if err != nil {
+		switch errors.Cause(err) {
+		case constants.ErrQuarantineNotFound:
+			errorCtrl.Error404(w, r)
+		default:
+			log.Error("Error %s", err)
+			errorCtrl.Error500(w, r)
+		}
+		return
+	}
+
+	helper.WriteResponseValueOK(w, r, report)
+}
+
+// QuarantinedRepoSalvagePOST rebuilds a quarantined repo from its intact
+// releases and moves it back into place.
+// Request: POST /admin/quarantine/:quarantineID/salvage
+func QuarantinedRepoSalvagePOST(w http.ResponseWriter, r *http.Request) {
+	if !authenticate(w, r) {
+		return
+	}
+
+	result, err := vcs.SalvageQuarantinedRepo(mux.Vars(r)["quarantineID"])
+	if err != nil {
+		switch errors.Cause(err) {
+		case constants.ErrQuarantineNotFound:
+			errorCtrl.Error404(w, r)
+		case constants.ErrPackageExists:
+			errorCtrl.Error(w, r, http.StatusConflict, "A live package with the same name already exists")
+		case constants.ErrNothingToSalvage:
+			errorCtrl.Error(w, r, http.StatusUnprocessableEntity, "No intact release found to salvage")
+		default:
+			log.Error("Error %s", err)
+			errorCtrl.Error500(w, r)
+		}
+		return
+	}
+
+	helper.WriteResponseValueOK(w, r, result)
+}
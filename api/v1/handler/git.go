@@ -0,0 +1,131 @@
+package handler
+
+import (
+	"compress/gzip"
+	"io"
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"github.com/pkg/errors"
+	"gopx.io/gopx-common/log"
+	"gopx.io/gopx-vcs-api/api/v1/constants"
+	"gopx.io/gopx-vcs-api/api/v1/controller/helper"
+	"gopx.io/gopx-vcs-api/api/v1/controller/vcs"
+	errorCtrl "gopx.io/gopx-vcs-api/pkg/controller/error"
+)
+
+// openUploadPackSession opens a git-upload-pack session on the package repo
+// requested by the route and writes the error response if it fails.
+func openUploadPackSession(w http.ResponseWriter, r *http.Request) (ups *vcs.UploadPackSession, ok bool) {
+	pkgName := vcs.PackageNameFromRepoPath(mux.Vars(r)["repoPath"])
+	if pkgName == "" {
+		errorCtrl.Error404(w, r)
+		return
+	}
+
+	ups, err := vcs.NewUploadPackSession(pkgName)
+	if err != nil {
+		switch errors.Cause(err) {
+		case constants.ErrPackageNotFound:
+			errorCtrl.Error404(w, r)
+		default:
+			log.Error("Error %s", err)
+			errorCtrl.Error500(w, r)
+		}
+		return
+	}
+
+	ok = true
+	return
+}
+
+// GitInfoRefsGET advertises the refs of a package repo to the smart HTTP
+// git clients.
+// Request: GET /:repoPath/info/refs?service=git-upload-pack
+func GitInfoRefsGET(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Query().Get("service") != vcs.UploadPackService {
+		errorCtrl.Error403(w, r)
+		return
+	}
+
+	ups, ok := openUploadPackSession(w, r)
+	if !ok {
+		return
+	}
+	defer ups.Close()
+
+	ar, err := ups.AdvertisedRefs(true)
+	if err != nil {
+		log.Error("Error %s", err)
+		errorCtrl.Error500(w, r)
+		return
+	}
+
+	w.Header().Set("Cache-Control", "no-cache")
+	helper.WriteStreamHeader(w, r, "application/x-git-upload-pack-advertisement", http.StatusOK)
+
+	err = ar.Encode(w)
+	if err != nil {
+		log.Error("Error %s", err)
+	}
+}
+
+// GitUploadPackPOST serves the fetches and clones of a package repo to the
+// smart HTTP git clients.
+// Request: POST /:repoPath/git-upload-pack
+func GitUploadPackPOST(w http.ResponseWriter, r *http.Request) {
+	if r.Header.Get("Content-Type") != "application/x-git-upload-pack-request" {
+		errorCtrl.Error(w, r, http.StatusUnsupportedMediaType, "Content-Type must be application/x-git-upload-pack-request")
+		return
+	}
+
+	ups, ok := openUploadPackSession(w, r)
+	if !ok {
+		return
+	}
+	defer ups.Close()
+
+	var body io.Reader = r.Body
+	if r.Header.Get("Content-Encoding") == "gzip" {
+		gr, err := gzip.NewReader(r.Body)
+		if err != nil {
+			errorCtrl.Error(w, r, http.StatusBadRequest, "Request body is not a valid gzip stream")
+			return
+		}
+		defer gr.Close()
+		body = gr
+	}
+
+	req, err := vcs.DecodeUploadPackRequest(body)
+	if err != nil {
+		switch errors.Cause(err) {
+		case constants.ErrInvalidUploadPack:
+			errorCtrl.Error(w, r, http.StatusBadRequest, err.Error())
+		default:
+			log.Error("Error %s", err)
+			errorCtrl.Error500(w, r)
+		}
+		return
+	}
+
+	resp, err := ups.UploadPack(r.Context(), req)
+	if err != nil {
+		switch errors.Cause(err) {
+		case constants.ErrInvalidUploadPack:
+			errorCtrl.Error(w, r, http.StatusBadRequest, err.Error())
+		default:
+			log.Error("Error %s", err)
+			errorCtrl.Error500(w, r)
+		}
+		return
+	}
+	defer resp.Close()
+
+	w.Header().Set("Cache-Control", "no-cache")
+	helper.WriteStreamHeader(w, r, "application/x-git-upload-pack-result", http.StatusOK)
+
+	err = resp.Encode(w)
+	if err != nil {
+		log.Error("Error %s", err)
+	}
+}
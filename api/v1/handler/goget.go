@@ -0,0 +1,54 @@
+package handler
+
+import (
+	"bytes"
+	"html/template"
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"github.com/pkg/errors"
+	"gopx.io/gopx-common/log"
+	"gopx.io/gopx-vcs-api/api/v1/constants"
+	"gopx.io/gopx-vcs-api/api/v1/controller/helper"
+	"gopx.io/gopx-vcs-api/api/v1/controller/vcs"
+	errorCtrl "gopx.io/gopx-vcs-api/pkg/controller/error"
+)
+
+var goImportTemplate = template.Must(template.New("go-import").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta name="go-import" content="{{.ImportPrefix}} {{.VCS}} {{.RepoURL}}">
+<meta name="go-source" content="{{.ImportPrefix}} {{.Home}} {{.Directory}} {{.File}}">
+</head>
+<body>
+go get {{.ImportPrefix}}
+</body>
+</html>
+`))
+
+// GoImportGET answers the go get requests of the vanity import paths with
+// the go-import and go-source meta tags.
+// Request: GET /:importPath?go-get=1
+func GoImportGET(w http.ResponseWriter, r *http.Request) {
+	meta, err := vcs.GoImportMeta(mux.Vars(r)["importPath"])
+	if err != nil {
+		switch errors.Cause(err) {
+		case constants.ErrPackageNotFound:
+			errorCtrl.Error404(w, r)
+		default:
+			log.Error("Error %s", err)
+			errorCtrl.Error500(w, r)
+		}
+		return
+	}
+
+	buff := bytes.Buffer{}
+	err = goImportTemplate.Execute(&buff, meta)
+	if err != nil {
+		log.Error("Error %s", err)
+		errorCtrl.Error500(w, r)
+		return
+	}
+
+	helper.WriteResponseContent(w, r, buff.Bytes(), "text/html; charset=utf-8", http.StatusOK)
+}
@@ -0,0 +1,107 @@
+package handler
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/gorilla/mux"
+	"github.com/pkg/errors"
+	"gopx.io/gopx-common/log"
+	"gopx.io/gopx-vcs-api/api/v1/constants"
+	"gopx.io/gopx-vcs-api/api/v1/controller/helper"
+	"gopx.io/gopx-vcs-api/api/v1/controller/vcs"
+	errorCtrl "gopx.io/gopx-vcs-api/pkg/controller/error"
+)
+
+// goModuleError writes the error response of a module proxy request.
+func goModuleError(w http.ResponseWriter, r *http.Request, err error) {
+	switch errors.Cause(err) {
+	case constants.ErrPackageNotFound, constants.ErrVersionNotFound:
+		errorCtrl.Error404(w, r)
+	case constants.ErrInvalidModuleZip:
+		log.Error("Error %s", err)
+		errorCtrl.Error(w, r, http.StatusGone, "Package version can't be served as a module zip")
+	default:
+		log.Error("Error %s", err)
+		errorCtrl.Error500(w, r)
+	}
+}
+
+// GoModuleVersionListGET lists the versions of a Go module.
+// Request: GET /:module/@v/list
+func GoModuleVersionListGET(w http.ResponseWriter, r *http.Request) {
+	versions, err := vcs.GoModuleVersions(mux.Vars(r)["module"])
+	if err != nil {
+		goModuleError(w, r, err)
+		return
+	}
+
+	var data string
+	if len(versions) > 0 {
+		data = strings.Join(versions, "\n") + "\n"
+	}
+
+	helper.WriteResponseContent(w, r, []byte(data), "text/plain; charset=utf-8", http.StatusOK)
+}
+
+// GoModuleLatestGET writes the info of the latest version of a Go module.
+// Request: GET /:module/@latest
+func GoModuleLatestGET(w http.ResponseWriter, r *http.Request) {
+	info, err := vcs.GoModuleLatest(mux.Vars(r)["module"])
+	if err != nil {
+		goModuleError(w, r, err)
+		return
+	}
+
+	helper.WriteResponseValueOK(w, r, info)
+}
+
+// GoModuleVersionInfoGET writes the info of a Go module version.
+// Request: GET /:module/@v/:version.info
+func GoModuleVersionInfoGET(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+
+	info, err := vcs.GoModuleVersionInfo(vars["module"], vars["version"])
+	if err != nil {
+		goModuleError(w, r, err)
+		return
+	}
+
+	helper.WriteResponseValueOK(w, r, info)
+}
+
+// GoModuleVersionModGET writes the go.mod file of a Go module version.
+// Request: GET /:module/@v/:version.mod
+func GoModuleVersionModGET(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+
+	goMod, err := vcs.GoModuleVersionMod(vars["module"], vars["version"])
+	if err != nil {
+		goModuleError(w, r, err)
+		return
+	}
+
+	helper.WriteResponseContent(w, r, goMod, "text/plain; charset=utf-8", http.StatusOK)
+}
+
+// GoModuleVersionZipGET streams the module zip of a Go module version.
+// Request: GET /:module/@v/:version.zip
+func GoModuleVersionZipGET(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+
+	mz, err := vcs.GoModuleVersionZip(vars["module"], vars["version"])
+	if err != nil {
+		goModuleError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=\"%s\"", mz.FileName))
+	w.Header().Set("Last-Modified", mz.ModTime.UTC().Format(http.TimeFormat))
+	helper.WriteStreamHeader(w, r, "application/zip", http.StatusOK)
+
+	err = mz.Write(w)
+	if err != nil {
+		log.Error("Error %s", err)
+	}
+}
@@ -3,11 +3,16 @@ package handler
 import (
 	"encoding/json"
 	"fmt"
+	"io"
+	"mime"
 	"net/http"
-	"strings"
+	"net/url"
+	"strconv"
 
 	"github.com/gorilla/mux"
+	"github.com/pkg/errors"
 	"gopx.io/gopx-common/log"
+	"gopx.io/gopx-vcs-api/api/v1/auth"
 	"gopx.io/gopx-vcs-api/api/v1/constants"
 	"gopx.io/gopx-vcs-api/api/v1/controller/helper"
 	"gopx.io/gopx-vcs-api/api/v1/controller/vcs"
@@ -15,10 +20,9 @@ import (
 	errorCtrl "gopx.io/gopx-vcs-api/pkg/controller/error"
 )
 
-// PackagesPOST registers a new package or a new version of an
-// existing package to the vcs registry.
-// Request: POST /packages
-func PackagesPOST(w http.ResponseWriter, r *http.Request) {
+// authenticate validates the authentication of the incoming http request
+// and writes the error response if it fails.
+func authenticate(w http.ResponseWriter, r *http.Request) bool {
 	ok, err := helper.AuthRequest(r.Header.Get("Authorization"))
 
 	if err != nil {
@@ -26,69 +30,320 @@ func PackagesPOST(w http.ResponseWriter, r *http.Request) {
 		case constants.ErrInternalServer:
 			log.Error("Error %s", err)
 			errorCtrl.Error500(w, r)
-			return
+			return false
 		default:
 			errorCtrl.Error(w, r, http.StatusUnauthorized, "Requires authentication")
-			return
+			return false
 		}
 	}
 
 	if !ok {
 		errorCtrl.Error(w, r, http.StatusUnauthorized, "Bad credentials")
+		return false
+	}
+
+	return true
+}
+
+// findReadablePackage checks whether the package exists and the incoming http
+// request is allowed to read it, and writes the error response otherwise.
+// Private packages require either the service auth key or a read key of the
+// package, and are reported as not found without them.
+func findReadablePackage(w http.ResponseWriter, r *http.Request, pkgName string) bool {
+	pkgType, ok, err := vcs.FindPackage(pkgName)
+	if err != nil {
+		log.Error("Error %s", err)
+		errorCtrl.Error500(w, r)
+		return false
+	}
+
+	if ok && pkgType == types.PackageTypePrivate {
+		ok, err = authorizePackageRead(r, pkgName)
+		if err != nil {
+			log.Error("Error %s", err)
+			errorCtrl.Error500(w, r)
+			return false
+		}
+	}
+
+	if !ok {
+		errorCtrl.Error404(w, r)
+		return false
+	}
+
+	return true
+}
+
+// authorizePackageRead validates the read access of the incoming http request
+// to a private package.
+func authorizePackageRead(r *http.Request, pkgName string) (ok bool, err error) {
+	authValue := r.Header.Get("Authorization")
+
+	authType, err := auth.Parse(authValue)
+	if err != nil {
+		return false, nil
+	}
+
+	switch v := authType.(type) {
+	case *auth.AuthenticationTypeReadKey:
+		return vcs.CheckPackageReadKey(pkgName, v.ReadKey())
+	default:
+		ok, err = helper.AuthRequest(authValue)
+		if err != nil && err != constants.ErrInternalServer {
+			return false, nil
+		}
+		return
+	}
+}
+
+// PackagesGET lists the visible packages in vcs registry page by page.
+// Request: GET /packages?page=&perPage=&prefix=
+func PackagesGET(w http.ResponseWriter, r *http.Request) {
+	page, perPage, err := helper.ParsePagination(r)
+	if err != nil {
+		errorCtrl.Error(w, r, http.StatusBadRequest, err.Error())
 		return
 	}
 
-	err = r.ParseMultipartForm(constants.MultiPartReaderMaxMemorySize)
+	prefix := r.URL.Query().Get("prefix")
+
+	pkgs, total, err := vcs.ListPackages(prefix, page, perPage)
+	if err != nil {
+		log.Error("Error %s", err)
+		errorCtrl.Error500(w, r)
+		return
+	}
+
+	helper.SetPaginationLinks(w, r, page, perPage, total)
+	helper.WriteResponseValueOK(w, r, pkgs)
+}
+
+// PackagesPOST registers a new package or a new version of an
+// existing package to the vcs registry.
+// Request: POST /packages
+func PackagesPOST(w http.ResponseWriter, r *http.Request) {
+	if !authenticate(w, r) {
+		return
+	}
+
+	mr, err := r.MultipartReader()
 	if err != nil {
 		errorCtrl.Error(w, r, http.StatusBadRequest, "Content-Type must be multipart/form-data")
 		return
 	}
 
-	mForm := r.MultipartForm
-	mfMeta := mForm.Value["meta"]
-	mfData := mForm.File["data"]
+	// The parts are read as they arrive, so the meta must precede the data
+	// to extract the package data while it's being uploaded.
+	var meta *types.PackageMeta
+	for {
+		part, err := mr.NextPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			errorCtrl.Error(w, r, http.StatusBadRequest, "Problems parsing multipart/form-data content")
+			return
+		}
+
+		switch part.FormName() {
+		case "meta":
+			meta = &types.PackageMeta{}
+			err = json.NewDecoder(io.LimitReader(part, constants.PackageMetaMaxSize)).Decode(meta)
+			if err != nil {
+				errorCtrl.Error(w, r, http.StatusBadRequest, "Problems parsing JSON meta data")
+				return
+			}
+		case "data":
+			if part.FileName() == "" {
+				errorCtrl.Error(w, r, http.StatusBadRequest, "Package data not found with param name data as a file")
+				return
+			}
+			if meta == nil {
+				errorCtrl.Error(w, r, http.StatusBadRequest, "Package meta must precede the package data")
+				return
+			}
+			publishPackage(w, r, meta, part)
+			return
+		}
+	}
 
-	if mfMeta == nil {
+	if meta == nil {
 		errorCtrl.Error(w, r, http.StatusBadRequest, "Package meta not found with param name meta")
 		return
 	}
 
-	if mfData == nil {
-		errorCtrl.Error(w, r, http.StatusBadRequest, "Package data not found with param name data as a file")
+	errorCtrl.Error(w, r, http.StatusBadRequest, "Package data not found with param name data as a file")
+}
+
+// PackageVersionPUT registers a new version of a package with the package
+// data as the raw request body. The package meta is taken from the headers,
+// and the archive format from the Content-Type. The release notes are
+// percent-encoded in their header as they're multiline.
+// Request: PUT /packages/:packageName/versions/:version
+func PackageVersionPUT(w http.ResponseWriter, r *http.Request) {
+	if !authenticate(w, r) {
+		return
+	}
+
+	vars := mux.Vars(r)
+	meta := &types.PackageMeta{
+		Name:    vars["packageName"],
+		Version: vars["version"],
+		Owner: types.PackageOwner{
+			Name:        r.Header.Get(constants.HeaderPackageOwnerName),
+			Username:    r.Header.Get(constants.HeaderPackageOwnerUsername),
+			PublicEmail: r.Header.Get(constants.HeaderPackageOwnerEmail),
+		},
+	}
+
+	switch pkgType := r.Header.Get(constants.HeaderPackageType); pkgType {
+	case types.PackageTypePublic.String():
+		meta.Type = types.PackageTypePublic
+	case types.PackageTypePrivate.String():
+		meta.Type = types.PackageTypePrivate
+	default:
+		errorCtrl.Error(w, r, http.StatusBadRequest, fmt.Sprintf("Unknown package type %q in %s header", pkgType, constants.HeaderPackageType))
 		return
 	}
 
-	pkgMeta := mfMeta[0]
-	pkgData, err := mfData[0].Open()
+	if meta.Owner.Username == "" {
+		errorCtrl.Error(w, r, http.StatusBadRequest, fmt.Sprintf("Package owner not found in %s header", constants.HeaderPackageOwnerUsername))
+		return
+	}
+
+	notes, err := url.PathUnescape(r.Header.Get(constants.HeaderPackageReleaseNotes))
 	if err != nil {
-		log.Error("Error %s", err)
-		errorCtrl.Error500(w, r)
+		errorCtrl.Error(w, r, http.StatusBadRequest, fmt.Sprintf("Release notes must be percent-encoded in %s header", constants.HeaderPackageReleaseNotes))
 		return
 	}
+	meta.ReleaseNotes = notes
+
+	format, ok := archiveFormatFromContentType(r.Header.Get("Content-Type"))
+	if !ok {
+		errorCtrl.Error(w, r, http.StatusUnsupportedMediaType, "Package data must be a zip, tar, tar.gz, tar.zst or tar.xz archive")
+		return
+	}
+	meta.Format = format
+
+	publishPackage(w, r, meta, r.Body)
+}
+
+// archiveMediaTypes maps the media types of the package data to the archive
+// formats. The format of the generic binary content is detected from the
+// content itself.
+var archiveMediaTypes = map[string]string{
+	"application/octet-stream": "",
+	"application/gzip":         constants.ArchiveFormatTarGZ,
+	"application/x-gzip":       constants.ArchiveFormatTarGZ,
+	"application/x-tar":        constants.ArchiveFormatTar,
+	"application/zip":          constants.ArchiveFormatZip,
+	"application/zstd":         constants.ArchiveFormatTarZst,
+	"application/x-xz":         constants.ArchiveFormatTarXZ,
+}
+
+func archiveFormatFromContentType(contentType string) (format string, ok bool) {
+	if contentType == "" {
+		return "", true
+	}
 
-	meta := types.PackageMeta{}
-	err = json.NewDecoder(strings.NewReader(pkgMeta)).Decode(&meta)
+	mediaType, _, err := mime.ParseMediaType(contentType)
 	if err != nil {
-		errorCtrl.Error(w, r, http.StatusBadRequest, "Problems parsing JSON meta data")
+		return "", false
+	}
+
+	format, ok = archiveMediaTypes[mediaType]
+	return
+}
+
+// publishPackage registers the package data as a new version of the package
+// and writes the published version as the response. The data is extracted
+// while it's being read. A retried request with the same Idempotency-Key and
+// content gets the response of the request which published the version.
+func publishPackage(w http.ResponseWriter, r *http.Request, meta *types.PackageMeta, data io.Reader) {
+	key := r.Header.Get(constants.HeaderIdempotencyKey)
+	if !validIdempotencyKey(key) {
+		errorCtrl.Error(w, r, http.StatusBadRequest, fmt.Sprintf("%s header must be up to %d printable ASCII characters", constants.HeaderIdempotencyKey, constants.IdempotencyKeyMaxLength))
 		return
 	}
+	meta.IdempotencyKey = key
 
+	var pkgVer *types.PackageVersion
+	var err error
 	switch meta.Type {
 	case types.PackageTypePublic:
-		err = vcs.RegisterPublicPackage(&meta, pkgData)
+		pkgVer, err = vcs.RegisterPublicPackage(meta, data)
 	case types.PackageTypePrivate:
-		err = vcs.RegisterPrivatePackage(&meta, pkgData)
+		pkgVer, err = vcs.RegisterPrivatePackage(meta, data)
 	default:
 		errorCtrl.Error(w, r, http.StatusBadRequest, fmt.Sprintf("Unknown package type %d", int(meta.Type)))
 		return
 	}
 	if err != nil {
-		log.Error("Error %s", err)
-		errorCtrl.Error500(w, r)
+		switch errors.Cause(err) {
+		case constants.ErrVersionDeleted:
+			errorCtrl.Error(w, r, http.StatusConflict, "Package version was deleted and can't be published again")
+		case constants.ErrPackageExists:
+			errorCtrl.Error(w, r, http.StatusConflict, "Package name is already taken by a package of another type")
+		case constants.ErrVersionExists, constants.ErrVersionConflict:
+			errorCtrl.Error(w, r, http.StatusConflict, err.Error())
+		case constants.ErrUnknownFormat:
+			errorCtrl.Error(w, r, http.StatusUnsupportedMediaType, "Package data must be a zip, tar, tar.gz, tar.zst or tar.xz archive")
+		case constants.ErrInvalidArchive:
+			errorCtrl.Error(w, r, http.StatusBadRequest, "Package data is not a valid archive")
+		case constants.ErrUnsafeArchive:
+			errorCtrl.Error(w, r, http.StatusBadRequest, "Package data has entries which are unsafe to extract")
+		case constants.ErrArchiveTooLarge:
+			errorCtrl.Error(w, r, http.StatusRequestEntityTooLarge, "Package data exceeds the package size limits")
+		case constants.ErrInvalidModule, constants.ErrInvalidVersion, constants.ErrPolicyViolation, constants.ErrInvalidNotes:
+			errorCtrl.Error(w, r, http.StatusUnprocessableEntity, err.Error())
+		default:
+			log.Error("Error %s", err)
+			errorCtrl.Error500(w, r)
+		}
+		return
+	}
+
+	helper.WriteResponseValue(w, r, pkgVer, http.StatusCreated)
+}
+
+// validIdempotencyKey checks that the idempotency key can be stored as a
+// release tag message trailer.
+func validIdempotencyKey(key string) bool {
+	if len(key) > constants.IdempotencyKeyMaxLength {
+		return false
+	}
+
+	for i := 0; i < len(key); i++ {
+		if key[i] < ' ' || key[i] > '~' {
+			return false
+		}
+	}
+
+	return true
+}
+
+// SinglePackageGET returns the summary of a package.
+// Request: GET /packages/:packageName
+func SinglePackageGET(w http.ResponseWriter, r *http.Request) {
+	inputPkgName := mux.Vars(r)["packageName"]
+
+	if !findReadablePackage(w, r, inputPkgName) {
+		return
+	}
+
+	summary, err := vcs.PackageSummary(inputPkgName)
+	if err != nil {
+		switch errors.Cause(err) {
+		case constants.ErrPackageNotFound:
+			errorCtrl.Error404(w, r)
+		default:
+			log.Error("Error %s", err)
+			errorCtrl.Error500(w, r)
+		}
 		return
 	}
 
-	helper.WriteResponse(w, r, []byte{}, http.StatusCreated)
+	helper.WriteResponseValueOK(w, r, summary)
 }
 
 // SinglePackageDELETE deletes a whole package from vcs registry.
@@ -96,7 +351,7 @@ func PackagesPOST(w http.ResponseWriter, r *http.Request) {
 func SinglePackageDELETE(w http.ResponseWriter, r *http.Request) {
 	inputPkgName := mux.Vars(r)["packageName"]
 
-	ok, err := vcs.PackageExists(inputPkgName)
+	_, ok, err := vcs.FindPackage(inputPkgName)
 	if err != nil {
 		log.Error("Error %s", err)
 		errorCtrl.Error500(w, r)
@@ -117,3 +372,339 @@ func SinglePackageDELETE(w http.ResponseWriter, r *http.Request) {
 
 	helper.WriteResponse(w, r, nil, http.StatusNoContent)
 }
+
+// SinglePackageVersionDELETE unpublishes a single version of a package.
+// Request: DELETE /packages/:packageName/versions/:version
+func SinglePackageVersionDELETE(w http.ResponseWriter, r *http.Request) {
+	if !authenticate(w, r) {
+		return
+	}
+
+	vars := mux.Vars(r)
+	inputPkgName := vars["packageName"]
+
+	_, ok, err := vcs.FindPackage(inputPkgName)
+	if err != nil {
+		log.Error("Error %s", err)
+		errorCtrl.Error500(w, r)
+		return
+	}
+
+	if !ok {
+		errorCtrl.Error404(w, r)
+		return
+	}
+
+	err = vcs.DeletePackageVersion(inputPkgName, vars["version"])
+	if err != nil {
+		switch errors.Cause(err) {
+		case constants.ErrVersionNotFound:
+			errorCtrl.Error404(w, r)
+		case constants.ErrLastVersion:
+			errorCtrl.Error(w, r, http.StatusConflict, "The only version of a package can't be deleted, delete the package instead")
+		default:
+			log.Error("Error %s", err)
+			errorCtrl.Error500(w, r)
+		}
+		return
+	}
+
+	helper.WriteResponse(w, r, nil, http.StatusNoContent)
+}
+
+// PackageVersionsGET lists the published versions of a package.
+// Request: GET /packages/:packageName/versions
+func PackageVersionsGET(w http.ResponseWriter, r *http.Request) {
+	inputPkgName := mux.Vars(r)["packageName"]
+
+	if !findReadablePackage(w, r, inputPkgName) {
+		return
+	}
+
+	versions, err := vcs.PackageVersions(inputPkgName)
+	if err != nil {
+		log.Error("Error %s", err)
+		errorCtrl.Error500(w, r)
+		return
+	}
+
+	helper.WriteResponseValueOK(w, r, versions)
+}
+
+// PackageVersionArchiveGET downloads the source archive of a package version.
+// Request: GET /packages/:packageName/versions/:version/archive.{tar.gz|zip}
+func PackageVersionArchiveGET(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	inputPkgName := vars["packageName"]
+
+	if !findReadablePackage(w, r, inputPkgName) {
+		return
+	}
+
+	archive, err := vcs.PackageVersionArchive(inputPkgName, vars["version"], vars["format"])
+	if err != nil {
+		switch errors.Cause(err) {
+		case constants.ErrVersionNotFound:
+			errorCtrl.Error404(w, r)
+		default:
+			log.Error("Error %s", err)
+			errorCtrl.Error500(w, r)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=\"%s\"", archive.FileName))
+	w.Header().Set("Last-Modified", archive.ModTime.UTC().Format(http.TimeFormat))
+	helper.WriteStreamHeader(w, r, archive.ContentType, http.StatusOK)
+
+	err = archive.Write(w)
+	if err != nil {
+		log.Error("Error %s", err)
+	}
+}
+
+// PackageVersionTreeGET lists a directory of a package version.
+// Request: GET /packages/:packageName/versions/:version/tree/:path
+func PackageVersionTreeGET(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	inputPkgName := vars["packageName"]
+
+	if !findReadablePackage(w, r, inputPkgName) {
+		return
+	}
+
+	entries, err := vcs.PackageVersionTree(inputPkgName, vars["version"], vars["path"])
+	if err != nil {
+		switch errors.Cause(err) {
+		case constants.ErrVersionNotFound, constants.ErrPathNotFound:
+			errorCtrl.Error404(w, r)
+		default:
+			log.Error("Error %s", err)
+			errorCtrl.Error500(w, r)
+		}
+		return
+	}
+
+	helper.WriteResponseValueOK(w, r, entries)
+}
+
+// PackageVersionNotesGET returns the release notes of a package version.
+// Request: GET /packages/:packageName/versions/:version/notes
+func PackageVersionNotesGET(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	inputPkgName := vars["packageName"]
+
+	if !findReadablePackage(w, r, inputPkgName) {
+		return
+	}
+
+	notes, err := vcs.PackageVersionNotes(inputPkgName, vars["version"])
+	if err != nil {
+		switch errors.Cause(err) {
+		case constants.ErrVersionNotFound:
+			errorCtrl.Error404(w, r)
+		default:
+			log.Error("Error %s", err)
+			errorCtrl.Error500(w, r)
+		}
+		return
+	}
+
+	helper.WriteResponseValueOK(w, r, notes)
+}
+
+// PackageVersionRawGET reads a raw file of a package version.
+// Request: GET /packages/:packageName/versions/:version/raw/:path
+func PackageVersionRawGET(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	inputPkgName := vars["packageName"]
+
+	if !findReadablePackage(w, r, inputPkgName) {
+		return
+	}
+
+	file, err := vcs.PackageVersionFile(inputPkgName, vars["version"], vars["path"])
+	if err != nil {
+		switch errors.Cause(err) {
+		case constants.ErrVersionNotFound, constants.ErrPathNotFound:
+			errorCtrl.Error404(w, r)
+		default:
+			log.Error("Error %s", err)
+			errorCtrl.Error500(w, r)
+		}
+		return
+	}
+
+	defer file.Close()
+
+	helper.ServeContent(w, r, file.Name, file.ModTime, file.Content)
+}
+
+// PackageCompareGET compares two versions of a package.
+// Request: GET /packages/:packageName/compare/:fromVersion...:toVersion?patch=
+func PackageCompareGET(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	inputPkgName := vars["packageName"]
+
+	withPatch := false
+	if v := r.URL.Query().Get("patch"); v != "" {
+		var err error
+		withPatch, err = strconv.ParseBool(v)
+		if err != nil {
+			errorCtrl.Error(w, r, http.StatusBadRequest, "Query param patch must be a boolean")
+			return
+		}
+	}
+
+	if !findReadablePackage(w, r, inputPkgName) {
+		return
+	}
+
+	cmp, err := vcs.ComparePackageVersions(inputPkgName, vars["fromVersion"], vars["toVersion"], withPatch)
+	if err != nil {
+		switch errors.Cause(err) {
+		case constants.ErrVersionNotFound:
+			errorCtrl.Error404(w, r)
+		default:
+			log.Error("Error %s", err)
+			errorCtrl.Error500(w, r)
+		}
+		return
+	}
+
+	helper.WriteResponseValueOK(w, r, cmp)
+}
+
+// PackageReadKeysGET lists the read keys of a private package.
+// Request: GET /packages/:packageName/read-keys
+func PackageReadKeysGET(w http.ResponseWriter, r *http.Request) {
+	if !authenticate(w, r) {
+		return
+	}
+
+	inputPkgName := mux.Vars(r)["packageName"]
+
+	keys, err := vcs.PackageReadKeys(inputPkgName)
+	if err != nil {
+		switch errors.Cause(err) {
+		case constants.ErrPackageNotFound:
+			errorCtrl.Error404(w, r)
+		default:
+			log.Error("Error %s", err)
+			errorCtrl.Error500(w, r)
+		}
+		return
+	}
+
+	helper.WriteResponseValueOK(w, r, keys)
+}
+
+// PackageReadKeysPOST creates a new read key for a private package.
+// Request: POST /packages/:packageName/read-keys
+func PackageReadKeysPOST(w http.ResponseWriter, r *http.Request) {
+	if !authenticate(w, r) {
+		return
+	}
+
+	inputPkgName := mux.Vars(r)["packageName"]
+
+	key, err := vcs.CreatePackageReadKey(inputPkgName)
+	if err != nil {
+		switch errors.Cause(err) {
+		case constants.ErrPackageNotFound:
+			errorCtrl.Error404(w, r)
+		default:
+			log.Error("Error %s", err)
+			errorCtrl.Error500(w, r)
+		}
+		return
+	}
+
+	helper.WriteResponseValue(w, r, key, http.StatusCreated)
+}
+
+// SinglePackageReadKeyDELETE revokes a read key of a private package.
+// Request: DELETE /packages/:packageName/read-keys/:keyID
+func SinglePackageReadKeyDELETE(w http.ResponseWriter, r *http.Request) {
+	if !authenticate(w, r) {
+		return
+	}
+
+	vars := mux.Vars(r)
+
+	err := vcs.DeletePackageReadKey(vars["packageName"], vars["keyID"])
+	if err != nil {
+		switch errors.Cause(err) {
+		case constants.ErrPackageNotFound, constants.ErrReadKeyNotFound:
+			errorCtrl.Error404(w, r)
+		default:
+			log.Error("Error %s", err)
+			errorCtrl.Error500(w, r)
+		}
+		return
+	}
+
+	helper.WriteResponse(w, r, nil, http.StatusNoContent)
+}
+
+// PackagePublishPolicyGET returns the publish policy of a package along with
+// its overrides of the registry policy.
+// Request: GET /packages/:packageName/publish-policy
+func PackagePublishPolicyGET(w http.ResponseWriter, r *http.Request) {
+	if !authenticate(w, r) {
+		return
+	}
+
+	inputPkgName := mux.Vars(r)["packageName"]
+
+	policy, err := vcs.PackagePublishPolicy(inputPkgName)
+	if err != nil {
+		switch errors.Cause(err) {
+		case constants.ErrPackageNotFound:
+			errorCtrl.Error404(w, r)
+		default:
+			log.Error("Error %s", err)
+			errorCtrl.Error500(w, r)
+		}
+		return
+	}
+
+	helper.WriteResponseValueOK(w, r, policy)
+}
+
+// PackagePublishPolicyPUT replaces the publish policy overrides of a package,
+// the omitted rules fall back to the registry policy.
+// Request: PUT /packages/:packageName/publish-policy
+func PackagePublishPolicyPUT(w http.ResponseWriter, r *http.Request) {
+	if !authenticate(w, r) {
+		return
+	}
+
+	inputPkgName := mux.Vars(r)["packageName"]
+
+	overrides := &types.PublishPolicyOverrides{}
+	err := json.NewDecoder(io.LimitReader(r.Body, constants.PackageMetaMaxSize)).Decode(overrides)
+	if err != nil {
+		errorCtrl.Error(w, r, http.StatusBadRequest, "Problems parsing JSON")
+		return
+	}
+
+	if overrides.MaxMajorJump != nil && *overrides.MaxMajorJump < 0 {
+		errorCtrl.Error(w, r, http.StatusUnprocessableEntity, "maxMajorJump can't be negative")
+		return
+	}
+
+	policy, err := vcs.UpdatePackagePublishPolicy(inputPkgName, overrides)
+	if err != nil {
+		switch errors.Cause(err) {
+		case constants.ErrPackageNotFound:
+			errorCtrl.Error404(w, r)
+		default:
+			log.Error("Error %s", err)
+			errorCtrl.Error500(w, r)
+		}
+		return
+	}
+
+	helper.WriteResponseValueOK(w, r, policy)
+}
@@ -0,0 +1,36 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/pkg/errors"
+	"gopx.io/gopx-common/log"
+	"gopx.io/gopx-vcs-api/api/v1/constants"
+	"gopx.io/gopx-vcs-api/api/v1/controller/helper"
+	"gopx.io/gopx-vcs-api/api/v1/controller/vcs"
+	errorCtrl "gopx.io/gopx-vcs-api/pkg/controller/error"
+)
+
+// SigningKeyGET returns the public key which verifies the release tag
+// signatures, an armored OpenPGP public key or an SSH allowed signers line.
+// Request: GET /signing-key
+func SigningKeyGET(w http.ResponseWriter, r *http.Request) {
+	key, format, err := vcs.SigningPublicKey()
+	if err != nil {
+		switch errors.Cause(err) {
+		case constants.ErrSigningDisabled:
+			errorCtrl.Error404(w, r)
+		default:
+			log.Error("Error %s", err)
+			errorCtrl.Error500(w, r)
+		}
+		return
+	}
+
+	contentType := "text/plain; charset=utf-8"
+	if format == constants.TagSignFormatOpenPGP {
+		contentType = "application/pgp-keys"
+	}
+
+	helper.WriteResponseContent(w, r, key, contentType, http.StatusOK)
+}
@@ -1,5 +1,9 @@
 package types
 
+import (
+	"time"
+)
+
 // PackageType represents type of package in vcs registry.
 type PackageType int
 
@@ -23,10 +27,15 @@ const (
 
 // PackageMeta represents the package meta data required on vcs registry request.
 type PackageMeta struct {
-	Type    PackageType  `json:"type"`
-	Name    string       `json:"name"`
-	Version string       `json:"version"`
-	Owner   PackageOwner `json:"owner"`
+	Type         PackageType  `json:"type"`
+	Name         string       `json:"name"`
+	Version      string       `json:"version"`
+	Owner        PackageOwner `json:"owner"`
+	Format       string       `json:"format,omitempty"`
+	ReleaseNotes string       `json:"releaseNotes,omitempty"`
+
+	// IdempotencyKey is taken from the Idempotency-Key request header.
+	IdempotencyKey string `json:"-"`
 }
 
 // PackageOwner represents the owner data required on vcs registry request.
@@ -35,3 +44,155 @@ type PackageOwner struct {
 	PublicEmail string `json:"publicEmail"`
 	Username    string `json:"username"`
 }
+
+// PackageVersion represents a published version of a package.
+type PackageVersion struct {
+	Version  string        `json:"version"`
+	Tag      string        `json:"tag"`
+	Commit   string        `json:"commit"`
+	Date     time.Time     `json:"date"`
+	Tagger   PackageTagger `json:"tagger"`
+	Sum      string        `json:"sum,omitempty"`
+	GoModSum string        `json:"goModSum,omitempty"`
+}
+
+// PackageReleaseNotes represents the markdown release notes of a package
+// version.
+type PackageReleaseNotes struct {
+	Version string `json:"version"`
+	Tag     string `json:"tag"`
+	Notes   string `json:"notes"`
+}
+
+// PackageTagger represents the signature of a package release tag.
+type PackageTagger struct {
+	Name  string `json:"name"`
+	Email string `json:"email"`
+}
+
+// PackageListItem represents a package entry in the package listing.
+type PackageListItem struct {
+	Name string `json:"name"`
+}
+
+// PackageSummary represents the overview of a package in vcs registry.
+type PackageSummary struct {
+	Name             string     `json:"name"`
+	LatestVersion    string     `json:"latestVersion"`
+	LatestPrerelease string     `json:"latestPrerelease"`
+	FirstPublished   *time.Time `json:"firstPublished"`
+	LastPublished    *time.Time `json:"lastPublished"`
+	Size             int64      `json:"size"`
+	Exported         bool       `json:"exported"`
+}
+
+// TreeEntry represents an entry of a directory listing in package source.
+type TreeEntry struct {
+	Name string `json:"name"`
+	Path string `json:"path"`
+	Type string `json:"type"`
+	Mode string `json:"mode"`
+	Size int64  `json:"size"`
+	Hash string `json:"hash"`
+}
+
+// PackageComparison represents the changes between two package versions.
+type PackageComparison struct {
+	From      string        `json:"from"`
+	To        string        `json:"to"`
+	Additions int           `json:"additions"`
+	Deletions int           `json:"deletions"`
+	Files     []*FileChange `json:"files"`
+	Patch     string        `json:"patch,omitempty"`
+}
+
+// FileChange represents a changed file between two package versions.
+type FileChange struct {
+	Path         string `json:"path"`
+	PreviousPath string `json:"previousPath,omitempty"`
+	Status       string `json:"status"`
+	Binary       bool   `json:"binary"`
+	Additions    int    `json:"additions"`
+	Deletions    int    `json:"deletions"`
+}
+
+// PackageSnapshot represents a soft deleted copy of a package repo.
+type PackageSnapshot struct {
+	ID        string    `json:"id"`
+	DeletedAt time.Time `json:"deletedAt"`
+	Size      int64     `json:"size"`
+	Private   bool      `json:"private"`
+}
+
+// QuarantineReport represents the diagnostic report of a corrupted repo
+// moved into quarantine.
+type QuarantineReport struct {
+	ID             string    `json:"id"`
+	Package        string    `json:"package"`
+	Error          string    `json:"error"`
+	MissingRefs    []string  `json:"missingRefs,omitempty"`
+	BrokenRefs     []string  `json:"brokenRefs,omitempty"`
+	MissingObjects []string  `json:"missingObjects,omitempty"`
+	InspectError   string    `json:"inspectError,omitempty"`
+	QuarantinedAt  time.Time `json:"quarantinedAt"`
+	Private        bool      `json:"private"`
+}
+
+// SalvageResult represents the outcome of salvaging a quarantined repo.
+type SalvageResult struct {
+	Package  string   `json:"package"`
+	Versions []string `json:"versions"`
+	Master   string   `json:"master"`
+}
+
+// PackageReadKey represents a read credential of a private package. The key
+// value is only present in the response of the key creation.
+type PackageReadKey struct {
+	ID        string    `json:"id"`
+	Key       string    `json:"key,omitempty"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// PublishPolicy represents the version rules checked on package publish.
+// The zero value accepts any new version. MaxMajorJump is the allowed
+// increase of the major version over the latest one, zero means no limit.
+type PublishPolicy struct {
+	DenyBackports             bool `json:"denyBackports"`
+	DenyPrereleaseAfterStable bool `json:"denyPrereleaseAfterStable"`
+	DenyV0Skips               bool `json:"denyV0Skips"`
+	MaxMajorJump              int  `json:"maxMajorJump"`
+}
+
+// PublishPolicyOverrides represents the publish policy rules set for a
+// single package. The unset rules fall back to the registry policy.
+type PublishPolicyOverrides struct {
+	DenyBackports             *bool `json:"denyBackports,omitempty"`
+	DenyPrereleaseAfterStable *bool `json:"denyPrereleaseAfterStable,omitempty"`
+	DenyV0Skips               *bool `json:"denyV0Skips,omitempty"`
+	MaxMajorJump              *int  `json:"maxMajorJump,omitempty"`
+}
+
+// PackagePublishPolicy represents the effective publish policy of a package
+// along with the overrides of the package.
+type PackagePublishPolicy struct {
+	Policy    PublishPolicy          `json:"policy"`
+	Overrides PublishPolicyOverrides `json:"overrides"`
+}
+
+// GoModuleInfo represents the version info of a Go module as served by the
+// module proxy protocol.
+type GoModuleInfo struct {
+	Version string    `json:"Version"`
+	Time    time.Time `json:"Time"`
+}
+
+// GoImportMeta represents the go-import and go-source meta tags of a package
+// which resolve its vanity import path.
+type GoImportMeta struct {
+	ImportPrefix string `json:"importPrefix"`
+	VCS          string `json:"vcs"`
+	RepoURL      string `json:"repoURL"`
+	Home         string `json:"home"`
+	Directory    string `json:"directory"`
+	File         string `json:"file"`
+}
@@ -7,11 +7,141 @@ import (
 
 // RegisterRoutes registers the routes for API version v1.
 func RegisterRoutes(r *mux.Router) {
+	r.Path("/packages").
+		Methods("GET").
+		HandlerFunc(handler.PackagesGET)
+
 	r.Path("/packages").
 		Methods("POST").
 		HandlerFunc(handler.PackagesPOST)
 
+	r.Path("/packages/{packageName}").
+		Methods("GET").
+		HandlerFunc(handler.SinglePackageGET)
+
 	r.Path("/packages/{packageName}").
 		Methods("DELETE").
 		HandlerFunc(handler.SinglePackageDELETE)
+
+	r.Path("/packages/{packageName}/versions").
+		Methods("GET").
+		HandlerFunc(handler.PackageVersionsGET)
+
+	r.Path("/packages/{packageName}/versions/{version}").
+		Methods("PUT").
+		HandlerFunc(handler.PackageVersionPUT)
+
+	r.Path("/packages/{packageName}/versions/{version}").
+		Methods("DELETE").
+		HandlerFunc(handler.SinglePackageVersionDELETE)
+
+	r.Path("/packages/{packageName}/versions/{version}/archive.{format:tar\\.gz|zip}").
+		Methods("GET").
+		HandlerFunc(handler.PackageVersionArchiveGET)
+
+	r.Path("/packages/{packageName}/versions/{version}/notes").
+		Methods("GET").
+		HandlerFunc(handler.PackageVersionNotesGET)
+
+	r.Path("/packages/{packageName}/versions/{version}/tree/{path:.*}").
+		Methods("GET").
+		HandlerFunc(handler.PackageVersionTreeGET)
+
+	r.Path("/packages/{packageName}/versions/{version}/raw/{path:.*}").
+		Methods("GET").
+		HandlerFunc(handler.PackageVersionRawGET)
+
+	r.Path("/packages/{packageName}/compare/{fromVersion:[^/]+?}...{toVersion:[^/]+}").
+		Methods("GET").
+		HandlerFunc(handler.PackageCompareGET)
+
+	r.Path("/packages/{packageName}/read-keys").
+		Methods("GET").
+		HandlerFunc(handler.PackageReadKeysGET)
+
+	r.Path("/packages/{packageName}/read-keys").
+		Methods("POST").
+		HandlerFunc(handler.PackageReadKeysPOST)
+
+	r.Path("/packages/{packageName}/read-keys/{keyID}").
+		Methods("DELETE").
+		HandlerFunc(handler.SinglePackageReadKeyDELETE)
+
+	r.Path("/packages/{packageName}/publish-policy").
+		Methods("GET").
+		HandlerFunc(handler.PackagePublishPolicyGET)
+
+	r.Path("/packages/{packageName}/publish-policy").
+		Methods("PUT").
+		HandlerFunc(handler.PackagePublishPolicyPUT)
+
+	r.Path("/signing-key").
+		Methods("GET").
+		HandlerFunc(handler.SigningKeyGET)
+
+	r.Path("/admin/packages/{packageName}/snapshots").
+		Methods("GET").
+		HandlerFunc(handler.PackageSnapshotsGET)
+
+	r.Path("/admin/packages/{packageName}/snapshots/{snapshotID}/restore").
+		Methods("POST").
+		HandlerFunc(handler.PackageSnapshotRestorePOST)
+
+	r.Path("/admin/quarantine").
+		Methods("GET").
+		HandlerFunc(handler.QuarantinedReposGET)
+
+	r.Path("/admin/quarantine/{quarantineID}").
+		Methods("GET").
+		HandlerFunc(handler.QuarantinedRepoGET)
+
+	r.Path("/admin/quarantine/{quarantineID}/salvage").
+		Methods("POST").
+		HandlerFunc(handler.QuarantinedRepoSalvagePOST)
+}
+
+// RegisterGitRoutes registers the git smart HTTP routes which serve the
+// exported package repos. The repo paths are resolved the same way as over
+// the git and ssh transports, with the configured repo extension.
+func RegisterGitRoutes(r *mux.Router) {
+	r.Path("/{repoPath}/info/refs").
+		Methods("GET").
+		HandlerFunc(handler.GitInfoRefsGET)
+
+	r.Path("/{repoPath}/git-upload-pack").
+		Methods("POST").
+		HandlerFunc(handler.GitUploadPackPOST)
+}
+
+// RegisterGoProxyRoutes registers the Go module proxy protocol routes which
+// serve the exported package repos as Go modules.
+func RegisterGoProxyRoutes(r *mux.Router) {
+	r.Path("/{module:.+}/@v/list").
+		Methods("GET").
+		HandlerFunc(handler.GoModuleVersionListGET)
+
+	r.Path("/{module:.+}/@v/{version}.info").
+		Methods("GET").
+		HandlerFunc(handler.GoModuleVersionInfoGET)
+
+	r.Path("/{module:.+}/@v/{version}.mod").
+		Methods("GET").
+		HandlerFunc(handler.GoModuleVersionModGET)
+
+	r.Path("/{module:.+}/@v/{version}.zip").
+		Methods("GET").
+		HandlerFunc(handler.GoModuleVersionZipGET)
+
+	r.Path("/{module:.+}/@latest").
+		Methods("GET").
+		HandlerFunc(handler.GoModuleLatestGET)
+}
+
+// RegisterGoGetRoutes registers the route which answers the go get requests
+// of the vanity import paths.
+func RegisterGoGetRoutes(r *mux.Router) {
+	r.Path("/{importPath:.+}").
+		Queries("go-get", "1").
+		Methods("GET").
+		HandlerFunc(handler.GoImportGET)
 }
@@ -13,6 +13,8 @@ import (
 	"gopx.io/gopx-common/log"
 	"gopx.io/gopx-common/str"
 	"gopx.io/gopx-vcs-api/pkg/config"
+	"gopx.io/gopx-vcs-api/pkg/gitdaemon"
+	"gopx.io/gopx-vcs-api/pkg/gitssh"
 	"gopx.io/gopx-vcs-api/pkg/route"
 )
 
@@ -40,6 +42,14 @@ func checkRequiredEnvSetup() {
 }
 
 func startServer() {
+	if config.Service.UseGitDaemon {
+		go startGitDaemon()
+	}
+
+	if config.Service.UseSSH {
+		go startSSH()
+	}
+
 	switch {
 	case config.Service.UseHTTP && config.Service.UseHTTPS:
 		go startHTTP()
@@ -89,6 +99,26 @@ func startHTTPS() {
 	log.Fatal("Error: %s", err) // err is always non-nill
 }
 
+func startGitDaemon() {
+	addr := gitDaemonAddr()
+	log.Info("GoPx VCS API service is running on: %s [GIT]", addr)
+	err := gitdaemon.ListenAndServe(addr, config.Service.ReadTimeout*time.Second, config.Service.IdleTimeout*time.Second)
+	log.Fatal("Error: %s", err) // err is always non-nill
+}
+
+func startSSH() {
+	addr := sshAddr()
+	log.Info("GoPx VCS API service is running on: %s [SSH]", addr)
+	err := gitssh.ListenAndServe(
+		addr,
+		config.Service.SSHHostKeyFile,
+		config.Service.SSHAuthorizedKeysFile,
+		config.Service.ReadTimeout*time.Second,
+		config.Service.IdleTimeout*time.Second,
+	)
+	log.Fatal("Error: %s", err) // err is always non-nill
+}
+
 func httpAddr() string {
 	return net.JoinHostPort(config.Service.Host, strconv.Itoa(config.Service.HTTPPort))
 }
@@ -96,3 +126,11 @@ func httpAddr() string {
 func httpsAddr() string {
 	return net.JoinHostPort(config.Service.Host, strconv.Itoa(config.Service.HTTPSPort))
 }
+
+func gitDaemonAddr() string {
+	return net.JoinHostPort(config.Service.Host, strconv.Itoa(config.Service.GitDaemonPort))
+}
+
+func sshAddr() string {
+	return net.JoinHostPort(config.Service.Host, strconv.Itoa(config.Service.SSHPort))
+}
@@ -19,7 +19,7 @@ type EnvConfig struct {
 var Env = new(EnvConfig)
 
 func init() {
-	bytes, err := ioutil.ReadFile(EnvConfigPath)
+	bytes, err := ioutil.ReadFile(configFilePath(EnvConfigPath))
 	if err != nil {
 		log.Fatal("Error: %s", err)
 	}
@@ -0,0 +1,21 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+)
+
+// ConfigDirEnv is the environment variable which holds the directory of the
+// configuration files, which are read relative to the working dir if it's
+// not set. The tests use it as they're run from the package dirs.
+const ConfigDirEnv = "GOPX_VCS_API_CONFIG_DIR"
+
+// configFilePath returns the path of the configuration file inside the
+// directory of ConfigDirEnv, or the path as it is if that isn't set.
+func configFilePath(p string) string {
+	dir := os.Getenv(ConfigDirEnv)
+	if dir == "" {
+		return p
+	}
+	return filepath.Join(dir, filepath.Base(p))
+}
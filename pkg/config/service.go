@@ -13,23 +13,29 @@ const ServiceConfigPath = "./config/service.json"
 
 // ServiceConfig represents API service related configurations.
 type ServiceConfig struct {
-	Host         string        `json:"host"`
-	UseHTTP      bool          `json:"useHTTP"`
-	HTTPPort     int           `json:"HTTPPort"`
-	UseHTTPS     bool          `json:"useHTTPS"`
-	HTTPSPort    int           `json:"HTTPSPort"`
-	CertFile     string        `json:"certFile"`
-	KeyFile      string        `json:"keyFile"`
-	ReadTimeout  time.Duration `json:"readTimeout"`
-	WriteTimeout time.Duration `json:"writeTimeout"`
-	IdleTimeout  time.Duration `json:"idleTimeout"`
+	Host                  string        `json:"host"`
+	UseHTTP               bool          `json:"useHTTP"`
+	HTTPPort              int           `json:"HTTPPort"`
+	UseHTTPS              bool          `json:"useHTTPS"`
+	HTTPSPort             int           `json:"HTTPSPort"`
+	UseGitDaemon          bool          `json:"useGitDaemon"`
+	GitDaemonPort         int           `json:"gitDaemonPort"`
+	UseSSH                bool          `json:"useSSH"`
+	SSHPort               int           `json:"SSHPort"`
+	SSHHostKeyFile        string        `json:"SSHHostKeyFile"`
+	SSHAuthorizedKeysFile string        `json:"SSHAuthorizedKeysFile"`
+	CertFile              string        `json:"certFile"`
+	KeyFile               string        `json:"keyFile"`
+	ReadTimeout           time.Duration `json:"readTimeout"`
+	WriteTimeout          time.Duration `json:"writeTimeout"`
+	IdleTimeout           time.Duration `json:"idleTimeout"`
 }
 
 // Service holds loaded API service related configurations.
 var Service = new(ServiceConfig)
 
 func init() {
-	bytes, err := ioutil.ReadFile(ServiceConfigPath)
+	bytes, err := ioutil.ReadFile(configFilePath(ServiceConfigPath))
 	if err != nil {
 		log.Fatal("Error: %s", err)
 	}
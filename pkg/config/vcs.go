@@ -3,6 +3,8 @@ package config
 import (
 	"encoding/json"
 	"io/ioutil"
+	"path/filepath"
+	"strings"
 
 	"gopx.io/gopx-common/log"
 )
@@ -12,15 +14,42 @@ const VCSConfigPath = "./config/vcs.json"
 
 // VCSConfig represents vcs related configurations.
 type VCSConfig struct {
-	RepoRoot string `json:"repoRoot"`
-	RepoExt  string `json:"repoExt"`
+	RepoRoot         string `json:"repoRoot"`
+	PrivateRepoRoot  string `json:"privateRepoRoot"`
+	RepoExt          string `json:"repoExt"`
+	ImportPathPrefix string `json:"importPathPrefix"`
+	GitBaseURL       string `json:"gitBaseURL"`
+	BrowseBaseURL    string `json:"browseBaseURL"`
+	TagSignFormat    string `json:"tagSignFormat"`
+	TagSignKeyFile   string `json:"tagSignKeyFile"`
+
+	// The limits of the package data extraction on publish, zero means no
+	// limit. The sizes are in bytes of the extracted content.
+	MaxPackageSize      int64 `json:"maxPackageSize"`
+	MaxPackageFileCount int   `json:"maxPackageFileCount"`
+	MaxPackageFileSize  int64 `json:"maxPackageFileSize"`
+	MaxCompressionRatio int64 `json:"maxCompressionRatio"`
+
+	// The version rules checked on publish, which can be overridden per
+	// package.
+	PublishPolicy PublishPolicy `json:"publishPolicy"`
+}
+
+// PublishPolicy represents the version rules checked on package publish.
+// The zero value accepts any new version. MaxMajorJump is the allowed
+// increase of the major version over the latest one, zero means no limit.
+type PublishPolicy struct {
+	DenyBackports             bool `json:"denyBackports"`
+	DenyPrereleaseAfterStable bool `json:"denyPrereleaseAfterStable"`
+	DenyV0Skips               bool `json:"denyV0Skips"`
+	MaxMajorJump              int  `json:"maxMajorJump"`
 }
 
 // VCS holds loaded VCS related configurations.
 var VCS = new(VCSConfig)
 
 func init() {
-	bytes, err := ioutil.ReadFile(VCSConfigPath)
+	bytes, err := ioutil.ReadFile(configFilePath(VCSConfigPath))
 	if err != nil {
 		log.Fatal("Error: %s", err)
 	}
@@ -28,4 +57,13 @@ func init() {
 	if err != nil {
 		log.Fatal("Error: %s", err)
 	}
+
+	// The private repos must never be created relative to the working dir
+	// or among the public ones.
+	if strings.TrimSpace(VCS.PrivateRepoRoot) == "" {
+		log.Fatal("Error: privateRepoRoot can't be empty in %s", VCSConfigPath)
+	}
+	if filepath.Clean(VCS.PrivateRepoRoot) == filepath.Clean(VCS.RepoRoot) {
+		log.Fatal("Error: privateRepoRoot must differ from repoRoot in %s", VCSConfigPath)
+	}
 }
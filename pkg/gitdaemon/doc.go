@@ -0,0 +1,4 @@
+/*
+Package gitdaemon serves the exported package repos over the native git protocol.
+*/
+package gitdaemon
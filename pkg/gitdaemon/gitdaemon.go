@@ -0,0 +1,115 @@
+package gitdaemon
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"gopkg.in/src-d/go-git.v4/plumbing/format/pktline"
+	"gopx.io/gopx-common/log"
+	"gopx.io/gopx-vcs-api/api/v1/constants"
+	"gopx.io/gopx-vcs-api/api/v1/controller/vcs"
+	"gopx.io/gopx-vcs-api/pkg/idleconn"
+)
+
+// ListenAndServe listens on the TCP network address addr and serves the
+// git-upload-pack requests of the incoming connections. The request line
+// must be received within readTimeout, then the connection is closed once
+// it's idle for idleTimeout.
+func ListenAndServe(addr string, readTimeout, idleTimeout time.Duration) error {
+	l, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	defer l.Close()
+
+	var tempDelay time.Duration
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			if ne, ok := err.(net.Error); ok && ne.Temporary() {
+				if tempDelay == 0 {
+					tempDelay = 5 * time.Millisecond
+				} else {
+					tempDelay *= 2
+				}
+				if tempDelay > time.Second {
+					tempDelay = time.Second
+				}
+				log.Error("Error: %s; retrying in %v", err, tempDelay)
+				time.Sleep(tempDelay)
+				continue
+			}
+			return err
+		}
+		tempDelay = 0
+
+		go serveConn(conn, readTimeout, idleTimeout)
+	}
+}
+
+func serveConn(rawConn net.Conn, readTimeout, idleTimeout time.Duration) {
+	defer rawConn.Close()
+
+	rawConn.SetReadDeadline(time.Now().Add(readTimeout))
+	s := pktline.NewScanner(rawConn)
+	if !s.Scan() {
+		return
+	}
+
+	// The negotiation and the pack transfer are bounded by the idle timeout
+	// of each read and write.
+	conn := idleconn.New(rawConn, idleTimeout)
+
+	service, repoPath := parseRequest(s.Bytes())
+	log.Info("GIT %s %s", service, repoPath)
+
+	if service != vcs.UploadPackService {
+		writeError(conn, "service not enabled")
+		return
+	}
+
+	ups, err := vcs.NewUploadPackSession(vcs.PackageNameFromRepoPath(repoPath))
+	if err != nil {
+		if errors.Cause(err) != constants.ErrPackageNotFound {
+			log.Error("Error %s", err)
+		}
+		writeError(conn, fmt.Sprintf("access denied or repository not exported: %s", repoPath))
+		return
+	}
+	defer ups.Close()
+
+	err = ups.Serve(context.Background(), conn, conn)
+	if err != nil {
+		if errors.Cause(err) == constants.ErrInvalidUploadPack {
+			writeError(conn, err.Error())
+			return
+		}
+		log.Error("Error %s", err)
+	}
+}
+
+// parseRequest parses the request line sent by the client at the start of
+// the connection, e.g. "git-upload-pack /foo.git\x00host=gopx.io\x00".
+func parseRequest(line []byte) (service, repoPath string) {
+	if i := bytes.IndexByte(line, 0); i >= 0 {
+		line = line[:i]
+	}
+
+	parts := strings.SplitN(strings.TrimSpace(string(line)), " ", 2)
+	if len(parts) != 2 {
+		return
+	}
+
+	service, repoPath = parts[0], parts[1]
+	return
+}
+
+func writeError(w io.Writer, msg string) {
+	pktline.NewEncoder(w).Encodef("ERR %s\n", msg)
+}
@@ -0,0 +1,4 @@
+/*
+Package gitssh serves the exported package repos over a read-only SSH transport.
+*/
+package gitssh
@@ -0,0 +1,211 @@
+package gitssh
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"golang.org/x/crypto/ssh"
+	"gopx.io/gopx-common/log"
+	"gopx.io/gopx-vcs-api/api/v1/constants"
+	"gopx.io/gopx-vcs-api/api/v1/controller/vcs"
+	"gopx.io/gopx-vcs-api/pkg/idleconn"
+)
+
+// ListenAndServe listens on the TCP network address addr and serves the
+// git-upload-pack commands of the incoming SSH connections. The clients are
+// authenticated against the public keys in the authorized keys file, which
+// is read on each connection so that the keys can be changed on the fly.
+// The SSH handshake must complete within handshakeTimeout, then the
+// connection is closed once it's idle for idleTimeout.
+func ListenAndServe(addr, hostKeyFile, authorizedKeysFile string, handshakeTimeout, idleTimeout time.Duration) error {
+	hostKeyBytes, err := ioutil.ReadFile(hostKeyFile)
+	if err != nil {
+		return errors.Wrap(err, "Couldn't read the host key")
+	}
+
+	hostKey, err := ssh.ParsePrivateKey(hostKeyBytes)
+	if err != nil {
+		return errors.Wrap(err, "Couldn't parse the host key")
+	}
+
+	sshConfig := &ssh.ServerConfig{
+		PublicKeyCallback: func(conn ssh.ConnMetadata, key ssh.PublicKey) (*ssh.Permissions, error) {
+			return authorizeKey(authorizedKeysFile, key)
+		},
+	}
+	sshConfig.AddHostKey(hostKey)
+
+	l, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	defer l.Close()
+
+	var tempDelay time.Duration
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			if ne, ok := err.(net.Error); ok && ne.Temporary() {
+				if tempDelay == 0 {
+					tempDelay = 5 * time.Millisecond
+				} else {
+					tempDelay *= 2
+				}
+				if tempDelay > time.Second {
+					tempDelay = time.Second
+				}
+				log.Error("Error: %s; retrying in %v", err, tempDelay)
+				time.Sleep(tempDelay)
+				continue
+			}
+			return err
+		}
+		tempDelay = 0
+
+		go serveConn(conn, sshConfig, handshakeTimeout, idleTimeout)
+	}
+}
+
+// authorizeKey checks whether the public key is listed in the authorized
+// keys file.
+func authorizeKey(authorizedKeysFile string, key ssh.PublicKey) (*ssh.Permissions, error) {
+	data, err := ioutil.ReadFile(authorizedKeysFile)
+	if err != nil {
+		log.Error("Error: %s", err)
+		return nil, errors.New("authorized keys are not available")
+	}
+
+	keyBytes := key.Marshal()
+	for len(data) > 0 {
+		authKey, _, _, rest, err := ssh.ParseAuthorizedKey(data)
+		if err != nil {
+			break
+		}
+		if bytes.Equal(authKey.Marshal(), keyBytes) {
+			return &ssh.Permissions{
+				Extensions: map[string]string{
+					"pubkey-fp": ssh.FingerprintSHA256(key),
+				},
+			}, nil
+		}
+		data = rest
+	}
+
+	return nil, errors.Errorf("unknown public key %s", ssh.FingerprintSHA256(key))
+}
+
+func serveConn(conn net.Conn, sshConfig *ssh.ServerConfig, handshakeTimeout, idleTimeout time.Duration) {
+	defer conn.Close()
+
+	// The idle timeout bounds each read and write, and the whole handshake
+	// is bounded by closing the connection if it doesn't complete in time.
+	handshakeTimer := time.AfterFunc(handshakeTimeout, func() { conn.Close() })
+	sConn, chans, reqs, err := ssh.NewServerConn(idleconn.New(conn, idleTimeout), sshConfig)
+	handshakeTimer.Stop()
+	if err != nil {
+		return
+	}
+	defer sConn.Close()
+
+	go ssh.DiscardRequests(reqs)
+
+	for newChan := range chans {
+		if newChan.ChannelType() != "session" {
+			newChan.Reject(ssh.UnknownChannelType, "only session channels are supported")
+			continue
+		}
+
+		ch, chReqs, err := newChan.Accept()
+		if err != nil {
+			continue
+		}
+
+		go serveSession(ch, chReqs, sConn.Permissions.Extensions["pubkey-fp"])
+	}
+}
+
+// serveSession serves the single git-upload-pack command of a session. The
+// environment requests are ignored, and any other request is refused.
+func serveSession(ch ssh.Channel, reqs <-chan *ssh.Request, keyFingerprint string) {
+	defer ch.Close()
+
+	for req := range reqs {
+		switch req.Type {
+		case "env":
+			req.Reply(true, nil)
+		case "exec":
+			var payload struct{ Command string }
+			if err := ssh.Unmarshal(req.Payload, &payload); err != nil {
+				req.Reply(false, nil)
+				continue
+			}
+			req.Reply(true, nil)
+
+			status := runCommand(ch, payload.Command, keyFingerprint)
+			ch.SendRequest("exit-status", false, ssh.Marshal(struct{ Status uint32 }{status}))
+			return
+		default:
+			req.Reply(false, nil)
+		}
+	}
+}
+
+// runCommand runs the exec command of a session and returns its exit status.
+func runCommand(ch ssh.Channel, command, keyFingerprint string) uint32 {
+	service, repoPath := parseCommand(command)
+	log.Info("SSH %s %s [%s]", service, repoPath, keyFingerprint)
+
+	if service != vcs.UploadPackService {
+		fmt.Fprintf(ch.Stderr(), "Only %s is allowed\n", vcs.UploadPackService)
+		return 1
+	}
+
+	ups, err := vcs.NewUploadPackSession(vcs.PackageNameFromRepoPath(repoPath))
+	if err != nil {
+		if errors.Cause(err) != constants.ErrPackageNotFound {
+			log.Error("Error %s", err)
+		}
+		fmt.Fprintf(ch.Stderr(), "Repository not found: %s\n", repoPath)
+		return 1
+	}
+	defer ups.Close()
+
+	err = ups.Serve(context.Background(), ch, ch)
+	if err != nil {
+		if errors.Cause(err) != constants.ErrInvalidUploadPack {
+			log.Error("Error %s", err)
+		}
+		fmt.Fprintf(ch.Stderr(), "%s\n", err)
+		return 1
+	}
+
+	return 0
+}
+
+// parseCommand parses the command sent by the git client, e.g.
+// "git-upload-pack '/foo.git'". The repo path is quoted by the client the
+// same way as for a shell.
+func parseCommand(command string) (service, repoPath string) {
+	parts := strings.SplitN(strings.TrimSpace(command), " ", 2)
+	if len(parts) != 2 {
+		return
+	}
+
+	service = parts[0]
+	repoPath = strings.TrimSpace(parts[1])
+	if len(repoPath) >= 2 && repoPath[0] == '\'' && repoPath[len(repoPath)-1] == '\'' {
+		repoPath = repoPath[1 : len(repoPath)-1]
+	}
+
+	if strings.ContainsAny(repoPath, "'\"\\") {
+		repoPath = ""
+	}
+
+	return
+}
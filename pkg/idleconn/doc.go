@@ -0,0 +1,4 @@
+/*
+Package idleconn provides network connections which time out when idle.
+*/
+package idleconn
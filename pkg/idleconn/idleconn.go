@@ -0,0 +1,40 @@
+package idleconn
+
+import (
+	"net"
+	"time"
+)
+
+// Conn is a net.Conn whose reads and writes fail if they don't complete
+// within the idle timeout, so that a stalled peer can't hold the connection
+// forever. A zero timeout means no timeout.
+type Conn struct {
+	net.Conn
+	timeout time.Duration
+}
+
+// New returns the connection with the idle timeout applied to each read and
+// write.
+func New(conn net.Conn, timeout time.Duration) *Conn {
+	return &Conn{Conn: conn, timeout: timeout}
+}
+
+func (c *Conn) Read(p []byte) (n int, err error) {
+	if c.timeout > 0 {
+		err = c.Conn.SetReadDeadline(time.Now().Add(c.timeout))
+		if err != nil {
+			return
+		}
+	}
+	return c.Conn.Read(p)
+}
+
+func (c *Conn) Write(p []byte) (n int, err error) {
+	if c.timeout > 0 {
+		err = c.Conn.SetWriteDeadline(time.Now().Add(c.timeout))
+		if err != nil {
+			return
+		}
+	}
+	return c.Conn.Write(p)
+}
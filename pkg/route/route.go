@@ -18,6 +18,12 @@ func Router() *mux.Router {
 
 	r.Use(loggingMiddleware)
 
+	// The git, module proxy and go get routes are registered first, otherwise
+	// the /v1 prefix would shadow the package named v1.
+	v1.RegisterGitRoutes(r)
+	v1.RegisterGoProxyRoutes(r)
+	v1.RegisterGoGetRoutes(r)
+
 	s1 := r.PathPrefix("/v1").Subrouter()
 	v1.RegisterRoutes(s1)
 
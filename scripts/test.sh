@@ -0,0 +1,7 @@
+#!/usr/bin/env bash
+
+# The packages read the config files on init, point them to the repo config
+export GOPX_VCS_API_CONFIG_DIR=$(pwd)/config
+
+# Run the tests
+go test ./...